@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wpcodevo/golang-gorm-postgres/initializers"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+)
+
+// Bootstraps administrator accounts. Registration is by invitation and only
+// admins can invite, so the first admin is made here.
+//
+//	go run admin/admin.go create -name <name> -email <email>
+//	go run admin/admin.go promote <email>
+//
+// create reads the password from standard input, promote gives an existing
+// account the admin role.
+
+func init() {
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		log.Fatal("🚀 Could not load environment variables", err)
+	}
+
+	initializers.ConnectDB(&config)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "create":
+		err = createAdmin(os.Args[2:])
+	case "promote":
+		err = promoteAdmin(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin create -name <name> -email <email> | promote <email>")
+	os.Exit(2)
+}
+
+func createAdmin(args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	name := flags.String("name", "", "name of the admin")
+	email := flags.String("email", "", "email address the admin signs in with")
+	flags.Parse(args)
+
+	if *name == "" || *email == "" {
+		usage()
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("could not read the password: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if len(password) < 8 {
+		return errors.New("the password must be at least 8 characters")
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := models.User{
+		Name:      *name,
+		Email:     strings.ToLower(*email),
+		Password:  hashedPassword,
+		Role:      models.RoleAdmin,
+		Verified:  true,
+		Provider:  "local",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := initializers.DB.Create(&user).Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique") {
+			return fmt.Errorf("%s already has an account, use promote instead", user.Email)
+		}
+		return err
+	}
+
+	fmt.Printf("Created admin %s\n", user.Email)
+	return nil
+}
+
+func promoteAdmin(args []string) error {
+	if len(args) != 1 {
+		usage()
+	}
+
+	email := strings.ToLower(args[0])
+	result := initializers.DB.Model(&models.User{}).Where("email = ?", email).Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no account with the email %s", email)
+	}
+
+	fmt.Printf("%s is now an admin\n", email)
+	return nil
+}
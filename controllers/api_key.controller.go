@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+type APIKeyController struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyController(DB *gorm.DB) APIKeyController {
+	return APIKeyController{DB}
+}
+
+// CreateAPIKey issues a new API key. The plain key is only returned once.
+// Keys act on behalf of user_id (the current user by default).
+func (kc *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload models.CreateAPIKeyInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	owner := currentUser
+	if payload.UserID != nil && *payload.UserID != currentUser.ID {
+		if err := kc.DB.First(&owner, "id = ?", *payload.UserID).Error; err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "User not found"})
+			return
+		}
+	}
+
+	for _, scope := range payload.Scopes {
+		if scope == models.PermAPIKeysManage || !models.JSONBStringList(models.AllPermissions).Contains(scope) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid scope: " + scope})
+			return
+		}
+		if !models.HasPermission(owner.Role, scope) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "The key owner's role does not grant scope: " + scope})
+			return
+		}
+	}
+
+	if payload.ExpiresAt != nil && payload.ExpiresAt.Before(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Expiry must be in the future"})
+		return
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+	key := models.APIKeyPrefix + secret
+
+	apiKey := models.APIKey{
+		ID:        uuid.New(),
+		Name:      payload.Name,
+		Prefix:    key[:len(models.APIKeyPrefix)+8],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    payload.Scopes,
+		UserID:    owner.ID,
+		ExpiresAt: payload.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := kc.DB.Create(&apiKey).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "key": key, "data": apiKey})
+}
+
+// FindAPIKeys lists every API key, newest first
+func (kc *APIKeyController) FindAPIKeys(ctx *gin.Context) {
+	var apiKeys []models.APIKey
+	if err := kc.DB.Preload("User").Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(apiKeys), "data": apiKeys})
+}
+
+// RevokeAPIKey permanently disables an API key
+func (kc *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	apiKeyID := ctx.Param("apiKeyId")
+
+	if _, err := uuid.Parse(apiKeyID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid API key ID format"})
+		return
+	}
+
+	result := kc.DB.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", apiKeyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No active API key with that ID exists"})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
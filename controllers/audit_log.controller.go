@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"gorm.io/gorm"
+)
+
+type AuditLogController struct {
+	DB *gorm.DB
+}
+
+func NewAuditLogController(DB *gorm.DB) AuditLogController {
+	return AuditLogController{DB}
+}
+
+// FindAuditLogs lists audit log entries, newest first. The history of one
+// record comes from the entityType and entityId path parameters, the
+// activity of one user from the userId path parameter. The entity_type,
+// entity_id, actor_id, action, from and to (RFC 3339) queries narrow it down.
+func (ac *AuditLogController) FindAuditLogs(ctx *gin.Context) {
+	var page = ctx.DefaultQuery("page", "1")
+	var limit = ctx.DefaultQuery("limit", "200")
+
+	intPage, _ := strconv.Atoi(page)
+	intLimit, _ := strconv.Atoi(limit)
+	offset := (intPage - 1) * intLimit
+
+	entityType := ctx.Param("entityType")
+	if entityType == "" {
+		entityType = ctx.Query("entity_type")
+	}
+	entityID := ctx.Param("entityId")
+	if entityID == "" {
+		entityID = ctx.Query("entity_id")
+	}
+	actorID := ctx.Param("userId")
+	if actorID == "" {
+		actorID = ctx.Query("actor_id")
+	}
+
+	query := ac.DB.Model(&models.AuditLog{})
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if actorID != "" {
+		if _, err := uuid.Parse(actorID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+			return
+		}
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action := ctx.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	for _, bound := range []struct{ param, condition string }{{"from", "created_at >= ?"}, {"to", "created_at < ?"}} {
+		value := ctx.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid " + bound.param + " time, use RFC 3339"})
+			return
+		}
+		query = query.Where(bound.condition, parsed)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var logs []models.AuditLog
+	if err := query.Order("created_at DESC").Limit(intLimit).Offset(offset).Find(&logs).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve audit log"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(logs), "total": total, "data": logs})
+}
@@ -2,29 +2,70 @@ package controllers
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/wpcodevo/golang-gorm-postgres/initializers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
 	"github.com/wpcodevo/golang-gorm-postgres/models"
 	"github.com/wpcodevo/golang-gorm-postgres/utils"
 	"gorm.io/gorm"
 )
 
 type AuthController struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Mailer utils.Mailer
+	Config *initializers.Config
+	Tokens *utils.TokenManager
 }
 
-func NewAuthController(DB *gorm.DB) AuthController {
-	return AuthController{DB}
+// Login throttling policy
+const (
+	failedLoginWindow    = 15 * time.Minute
+	maxFailedLoginsPerIP = 20
+	loginDelayThreshold  = 3
+	maxLoginDelay        = time.Minute
+	lockoutThreshold     = 10
+	lockoutDuration      = 15 * time.Minute
+)
+
+// credentialFailures are the login history reasons of attempts that checked
+// wrong credentials. Attempts turned away by the throttle itself are left
+// out, or a throttled client would keep itself throttled.
+var credentialFailures = []string{"unknown_email", "invalid_password", "invalid_two_factor_code"}
+
+// Two-factor authentication settings
+const (
+	twoFactorChallengeTTL  = 5 * time.Minute
+	challengeTokenType     = "mfa_challenge"
+	challengePurposeVerify = "verify"
+	challengePurposeEnroll = "enroll"
+	recoveryCodeCount      = 10
+)
+
+func NewAuthController(DB *gorm.DB, Mailer utils.Mailer, Config *initializers.Config, Tokens *utils.TokenManager) AuthController {
+	return AuthController{DB, Mailer, Config, Tokens}
 }
 
-// SignUp User
+// SignUp User creates an unverified account when open registration is enabled
 func (ac *AuthController) SignUpUser(ctx *gin.Context) {
 	var payload *models.SignUpInput
 
+	if !ac.Config.AllowRegistration {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Registration is by invitation only"})
+		return
+	}
+	// The account could never be verified
+	if !utils.MailEnabled(ac.Mailer) {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "message": "Registration is unavailable, email is not configured on this server"})
+		return
+	}
+
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
 		return
@@ -46,15 +87,15 @@ func (ac *AuthController) SignUpUser(ctx *gin.Context) {
 		Name:      payload.Name,
 		Email:     strings.ToLower(payload.Email),
 		Password:  hashedPassword,
-		Role:      "user",
-		Verified:  true,
+		Role:      models.RoleReadOnly,
+		Verified:  false,
 		Photo:     payload.Photo,
 		Provider:  "local",
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	result := ac.DB.Create(&newUser)
+	result := ac.DB.WithContext(ctx).Create(&newUser)
 
 	if result.Error != nil && strings.Contains(result.Error.Error(), "duplicate key value violates unique") {
 		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "User with that email already exists"})
@@ -64,6 +105,18 @@ func (ac *AuthController) SignUpUser(ctx *gin.Context) {
 		return
 	}
 
+	token, err := ac.createUserToken(newUser.ID, models.TokenPurposeEmailVerification, ac.Config.EmailVerificationTokenExpiresIn)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create email verification token"})
+		return
+	}
+
+	verifyURL := fmt.Sprintf("%s/verify-email?token=%s", ac.Config.ClientOrigin, token)
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below. The link expires in %s.\n\n%s", newUser.Name, ac.Config.EmailVerificationTokenExpiresIn, verifyURL)
+	if err := ac.Mailer.Send(newUser.Email, "Verify your email address", body); err != nil {
+		log.Println("Failed to send verification email:", err)
+	}
+
 	userResponse := &models.UserResponse{
 		ID:        newUser.ID,
 		Name:      newUser.Name,
@@ -77,84 +130,421 @@ func (ac *AuthController) SignUpUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": gin.H{"user": userResponse}})
 }
 
-func (ac *AuthController) ResetPassword(ctx *gin.Context) {
-	var payload *models.SignUpInput
-	userID := ctx.Param("userId")
+func (ac *AuthController) SignInUser(ctx *gin.Context) {
+	var payload *models.SignInInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	email := strings.ToLower(payload.Email)
+	now := time.Now()
+
+	// Throttle clients that keep failing, whatever account they target
+	var ipFailures int64
+	ac.DB.Model(&models.LoginAttempt{}).
+		Where("client_ip = ? AND success = ? AND reason IN ? AND created_at > ?", ctx.ClientIP(), false, credentialFailures, now.Add(-failedLoginWindow)).
+		Count(&ipFailures)
+	if ipFailures >= maxFailedLoginsPerIP {
+		ac.recordLoginAttempt(ctx, email, nil, false, "ip_throttled")
+		ctx.Header("Retry-After", strconv.Itoa(int(failedLoginWindow.Seconds())))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"status": "fail", "message": "Too many failed login attempts, please try again later"})
+		return
+	}
+
 	var user models.User
+	result := ac.DB.First(&user, "email = ?", email)
+	if result.Error != nil {
+		ac.recordLoginAttempt(ctx, email, nil, false, "unknown_email")
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid email or Password"})
+		return
+	}
+
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		ac.recordLoginAttempt(ctx, email, &user.ID, false, "locked")
+		ctx.Header("Retry-After", strconv.Itoa(int(user.LockedUntil.Sub(now).Seconds())+1))
+		ctx.JSON(http.StatusLocked, gin.H{"status": "fail", "message": "Account is temporarily locked after too many failed login attempts"})
+		return
+	}
+
+	// Each failure past the threshold doubles the wait before the next attempt
+	if delay := loginDelay(user.FailedLoginCount); delay > 0 && user.LastFailedLoginAt != nil {
+		if retryAt := user.LastFailedLoginAt.Add(delay); retryAt.After(now) {
+			ac.recordLoginAttempt(ctx, email, &user.ID, false, "throttled")
+			ctx.Header("Retry-After", strconv.Itoa(int(retryAt.Sub(now).Seconds())+1))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{"status": "fail", "message": "Too many failed login attempts, please wait before trying again"})
+			return
+		}
+	}
+
+	if err := utils.VerifyPassword(user.Password, payload.Password); err != nil {
+		ac.registerFailedLogin(&user)
+		ac.recordLoginAttempt(ctx, email, &user.ID, false, "invalid_password")
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid email or Password"})
+		return
+	}
+
+	if !user.Verified {
+		ac.recordLoginAttempt(ctx, email, &user.ID, false, "unverified")
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Please verify your email address before logging in"})
+		return
+	}
+
+	// Accounts with a second factor get a short-lived challenge instead of tokens
+	if user.TwoFactorEnabled {
+		challenge, err := ac.Tokens.CreateAccessToken(twoFactorChallengeTTL, user.ID, map[string]interface{}{"typ": challengeTokenType, "purpose": challengePurposeVerify})
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "success", "two_factor_required": true, "challenge_token": challenge})
+		return
+	}
+
+	if ac.roleRequiresTwoFactor(user.Role) {
+		challenge, err := ac.Tokens.CreateAccessToken(twoFactorChallengeTTL, user.ID, map[string]interface{}{"typ": challengeTokenType, "purpose": challengePurposeEnroll})
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "success", "two_factor_enrollment_required": true, "challenge_token": challenge})
+		return
+	}
+
+	ac.completeSignIn(ctx, user)
+}
+
+// completeSignIn resets the failed login counter, records the login and issues the tokens
+func (ac *AuthController) completeSignIn(ctx *gin.Context, user models.User, extra ...gin.H) {
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		ac.DB.Model(&user).Updates(map[string]interface{}{"failed_login_count": 0, "locked_until": nil, "last_failed_login_at": nil})
+	}
+	ac.recordLoginAttempt(ctx, user.Email, &user.ID, true, "")
+
+	session, access_token, refresh_token, err := ac.createSession(ctx, user, uuid.New())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.SetCookie("access_token", access_token, ac.Config.AccessTokenMaxAge*60, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", refresh_token, ac.Config.RefreshTokenMaxAge*60, "/", "localhost", false, true)
+	ctx.SetCookie("logged_in", "true", ac.Config.AccessTokenMaxAge*60, "/", "localhost", false, false)
+
+	response := gin.H{"status": "success", "access_token": access_token, "refresh_token": refresh_token, "session_id": session.ID, "user_profile": user}
+	for _, fields := range extra {
+		for key, value := range fields {
+			response[key] = value
+		}
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// VerifyTwoFactor completes a sign in with a TOTP code or a recovery code
+func (ac *AuthController) VerifyTwoFactor(ctx *gin.Context) {
+	var payload *models.TwoFactorVerifyInput
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	if payload.Password != payload.PasswordConfirm {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Passwords do not match"})
+	user, err := ac.userFromChallenge(payload.ChallengeToken, challengePurposeVerify)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(payload.Password)
+	now := time.Now()
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		ac.recordLoginAttempt(ctx, user.Email, &user.ID, false, "locked")
+		ctx.JSON(http.StatusLocked, gin.H{"status": "fail", "message": "Account is temporarily locked after too many failed login attempts"})
+		return
+	}
+
+	// Codes are guessable, so failures count towards the lockout like bad passwords
+	verified := false
+	if payload.Code != "" {
+		if step, ok := utils.ValidateTOTP(user.TOTPSecret, payload.Code, now); ok && step > user.TOTPLastUsedStep {
+			verified = ac.DB.Model(&models.User{}).
+				Where("id = ? AND totp_last_used_step < ?", user.ID, step).
+				Update("totp_last_used_step", step).RowsAffected == 1
+		}
+	} else if payload.RecoveryCode != "" {
+		verified = ac.consumeRecoveryCode(&user, payload.RecoveryCode)
+	}
+
+	if !verified {
+		ac.registerFailedLogin(&user)
+		ac.recordLoginAttempt(ctx, user.Email, &user.ID, false, "invalid_two_factor_code")
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid authentication code"})
+		return
+	}
+
+	ac.completeSignIn(ctx, user)
+}
+
+// SetupTwoFactor generates a new TOTP secret for the current user, or for the
+// user of an enrollment challenge when their role requires a second factor.
+// The secret only becomes active once EnableTwoFactor confirms a code.
+func (ac *AuthController) SetupTwoFactor(ctx *gin.Context) {
+	var payload models.TwoFactorSetupInput
+	ctx.ShouldBindJSON(&payload)
+
+	user, err := ac.twoFactorEnrollmentUser(ctx, payload.ChallengeToken)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": err.Error()})
+		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	// Find user by ID
-	if err := ac.DB.First(&user, "id = ?", userID).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+	if user.TwoFactorEnabled {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "Two-factor authentication is already enabled"})
 		return
 	}
 
-	user.Password = hashedPassword
-	if err := ac.DB.Save(&user).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update password"})
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	if err := ac.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_used_step": 0}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to save two-factor secret"})
+		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": user})
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(ac.Config.TOTPIssuer, user.Email, secret),
+	}})
 }
 
-func (ac *AuthController) SignInUser(ctx *gin.Context) {
-	var payload *models.SignInInput
+// EnableTwoFactor confirms the secret from SetupTwoFactor with a code and
+// returns the recovery codes. For an enrollment challenge it also signs the user in.
+func (ac *AuthController) EnableTwoFactor(ctx *gin.Context) {
+	var payload *models.TwoFactorEnableInput
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	var user models.User
-	result := ac.DB.First(&user, "email = ?", strings.ToLower(payload.Email))
-	if result.Error != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid email or Password"})
+	user, err := ac.twoFactorEnrollmentUser(ctx, payload.ChallengeToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	if err := utils.VerifyPassword(user.Password, payload.Password); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid email or Password"})
+	if user.TwoFactorEnabled {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "Two-factor authentication is already enabled"})
 		return
 	}
 
-	config, _ := initializers.LoadConfig(".")
+	if user.TOTPSecret == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Set up two-factor authentication first"})
+		return
+	}
 
-	// Generate Tokens
-	access_token, err := utils.CreateToken(config.AccessTokenExpiresIn, user.ID, config.AccessTokenPrivateKey)
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, payload.Code, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid authentication code"})
+		return
+	}
+
+	recoveryCodes, hashes, err := newRecoveryCodes()
 	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	if err := ac.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{
+		"two_factor_enabled":   true,
+		"totp_last_used_step":  step,
+		"recovery_code_hashes": hashes,
+		"updated_at":           time.Now(),
+	}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to enable two-factor authentication"})
+		return
+	}
+	middleware.InvalidateUser(user.ID)
+
+	if payload.ChallengeToken != "" {
+		ac.completeSignIn(ctx, user, gin.H{"recovery_codes": recoveryCodes})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"recovery_codes": recoveryCodes}})
+}
+
+// DisableTwoFactor turns the second factor off for the current user, unless their role requires it
+func (ac *AuthController) DisableTwoFactor(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.TwoFactorDisableInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	refresh_token, err := utils.CreateToken(config.RefreshTokenExpiresIn, user.ID, config.RefreshTokenPrivateKey)
-	if err != nil {
+	if ac.roleRequiresTwoFactor(currentUser.Role) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Two-factor authentication is required for your role"})
+		return
+	}
+
+	if !currentUser.TwoFactorEnabled {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if err := utils.VerifyPassword(currentUser.Password, payload.Password); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Password is incorrect"})
+		return
+	}
+
+	if _, ok := utils.ValidateTOTP(currentUser.TOTPSecret, payload.Code, time.Now()); !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid authentication code"})
+		return
+	}
+
+	if err := clearTwoFactor(ac.DB.WithContext(ctx), currentUser.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (ac *AuthController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.TwoFactorEnableInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	ctx.SetCookie("access_token", access_token, config.AccessTokenMaxAge*60, "/", "localhost", false, true)
-	ctx.SetCookie("refresh_token", refresh_token, config.RefreshTokenMaxAge*60, "/", "localhost", false, true)
-	ctx.SetCookie("logged_in", "true", config.AccessTokenMaxAge*60, "/", "localhost", false, false)
+	if !currentUser.TwoFactorEnabled {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if _, ok := utils.ValidateTOTP(currentUser.TOTPSecret, payload.Code, time.Now()); !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid authentication code"})
+		return
+	}
+
+	recoveryCodes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "access_token": access_token, "refresh_token": refresh_token, "user_profile": user})
+	if err := ac.DB.WithContext(ctx).Model(&currentUser).Update("recovery_code_hashes", hashes).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to save recovery codes"})
+		return
+	}
+	middleware.InvalidateUser(currentUser.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"recovery_codes": recoveryCodes}})
 }
 
-// Refresh Access Token
+// twoFactorEnrollmentUser returns the logged in user, or the user of an
+// enrollment challenge when the request carries one
+func (ac *AuthController) twoFactorEnrollmentUser(ctx *gin.Context, challengeToken string) (models.User, error) {
+	if challengeToken != "" {
+		return ac.userFromChallenge(challengeToken, challengePurposeEnroll)
+	}
+
+	value, exists := ctx.Get("currentUser")
+	if !exists {
+		return models.User{}, fmt.Errorf("You are not logged in")
+	}
+
+	// Reload to see a secret stored by a previous setup call
+	var user models.User
+	if err := ac.DB.First(&user, "id = ?", value.(models.User).ID).Error; err != nil {
+		return user, fmt.Errorf("the user belonging to this token no logger exists")
+	}
+	return user, nil
+}
+
+// userFromChallenge validates a two-factor challenge token issued by SignInUser
+func (ac *AuthController) userFromChallenge(challengeToken string, purpose string) (models.User, error) {
+	var user models.User
+
+	claims, err := ac.Tokens.ValidateAccessToken(challengeToken)
+	if err != nil || claims["typ"] != challengeTokenType || claims["purpose"] != purpose {
+		return user, fmt.Errorf("invalid or expired challenge, please log in again")
+	}
+
+	if err := ac.DB.First(&user, "id = ?", fmt.Sprint(claims["sub"])).Error; err != nil {
+		return user, fmt.Errorf("the user belonging to this token no logger exists")
+	}
+	return user, nil
+}
+
+// consumeRecoveryCode removes a matching recovery code so it cannot be used
+// again. The update only applies to the codes it read, so of two sign-ins
+// racing with the same code only one succeeds.
+func (ac *AuthController) consumeRecoveryCode(user *models.User, code string) bool {
+	hash := utils.HashToken(strings.ToLower(strings.TrimSpace(code)))
+	if !user.RecoveryCodeHashes.Contains(hash) {
+		return false
+	}
+
+	remaining := models.JSONBStringList{}
+	for _, existing := range user.RecoveryCodeHashes {
+		if existing != hash {
+			remaining = append(remaining, existing)
+		}
+	}
+
+	result := ac.DB.Model(user).
+		Where("recovery_code_hashes = ?", user.RecoveryCodeHashes).
+		Update("recovery_code_hashes", remaining)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// roleRequiresTwoFactor reports whether an admin made a second factor mandatory for the role
+func (ac *AuthController) roleRequiresTwoFactor(role string) bool {
+	var policy models.RolePolicy
+	if err := ac.DB.First(&policy, "role = ?", role).Error; err != nil {
+		return false
+	}
+	return policy.RequireTwoFactor
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store
+func newRecoveryCodes() ([]string, models.JSONBStringList, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make(models.JSONBStringList, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// clearTwoFactor removes the second factor of a user
+func clearTwoFactor(db *gorm.DB, userID uuid.UUID) error {
+	defer middleware.InvalidateUser(userID)
+	return db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"two_factor_enabled":   false,
+		"totp_secret":          "",
+		"totp_last_used_step":  0,
+		"recovery_code_hashes": models.JSONBStringList{},
+		"updated_at":           time.Now(),
+	}).Error
+}
+
+// Refresh Access Token rotates the refresh token. Presenting a refresh token
+// that was already rotated or revoked means it leaked, so the whole session
+// family is revoked.
 func (ac *AuthController) RefreshAccessToken(ctx *gin.Context) {
 	var payload *models.RefreshTokenRequest
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
@@ -162,37 +552,522 @@ func (ac *AuthController) RefreshAccessToken(ctx *gin.Context) {
 		return
 	}
 
-	config, _ := initializers.LoadConfig(".")
-
-	sub, err := utils.ValidateToken(payload.RefreshToken, config.RefreshTokenPublicKey)
+	claims, err := ac.Tokens.ValidateRefreshToken(payload.RefreshToken)
 	if err != nil {
 		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
+	sub := claims["sub"]
+
+	var session models.Session
+	result := ac.DB.First(&session, "token_hash = ?", utils.HashToken(payload.RefreshToken))
+	if result.Error != nil || session.UserID.String() != fmt.Sprint(sub) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Invalid refresh token"})
+		return
+	}
+
+	if session.RevokedAt != nil || session.RotatedAt != nil {
+		revokeSessionFamily(ac.DB, session.FamilyID)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Refresh token reuse detected, please log in again"})
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Session expired, please log in again"})
+		return
+	}
 
 	var user models.User
-	result := ac.DB.First(&user, "id = ?", fmt.Sprint(sub))
+	result = ac.DB.First(&user, "id = ?", session.UserID)
 	if result.Error != nil {
 		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "the user belonging to this token no logger exists"})
 		return
 	}
 
-	access_token, err := utils.CreateToken(config.AccessTokenExpiresIn, user.ID, config.AccessTokenPrivateKey)
+	// Only one concurrent refresh may win the rotation
+	now := time.Now()
+	rotated := ac.DB.Model(&models.Session{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", session.ID).
+		Update("rotated_at", now)
+	if rotated.Error != nil || rotated.RowsAffected == 0 {
+		revokeSessionFamily(ac.DB, session.FamilyID)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Refresh token reuse detected, please log in again"})
+		return
+	}
+
+	_, access_token, refresh_token, err := ac.createSession(ctx, user, session.FamilyID)
 	if err != nil {
 		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	ctx.SetCookie("access_token", access_token, config.AccessTokenMaxAge*60, "/", "localhost", false, true)
-	ctx.SetCookie("logged_in", "true", config.AccessTokenMaxAge*60, "/", "localhost", false, false)
+	ctx.SetCookie("access_token", access_token, ac.Config.AccessTokenMaxAge*60, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", refresh_token, ac.Config.RefreshTokenMaxAge*60, "/", "localhost", false, true)
+	ctx.SetCookie("logged_in", "true", ac.Config.AccessTokenMaxAge*60, "/", "localhost", false, false)
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "access_token": access_token})
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "access_token": access_token, "refresh_token": refresh_token})
 }
 
 func (ac *AuthController) LogoutUser(ctx *gin.Context) {
+	currentSession := ctx.MustGet("currentSession").(models.Session)
+
+	if err := revokeSessionFamily(ac.DB, currentSession.FamilyID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to revoke session"})
+		return
+	}
+
 	ctx.SetCookie("access_token", "", -1, "/", "localhost", false, true)
 	ctx.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
 	ctx.SetCookie("logged_in", "", -1, "/", "localhost", false, false)
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success"})
 }
+
+// FindSessions lists the devices the current user is logged in on
+func (ac *AuthController) FindSessions(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	currentSession := ctx.MustGet("currentSession").(models.Session)
+
+	var sessions []models.Session
+	result := ac.DB.Where("user_id = ? AND revoked_at IS NULL AND rotated_at IS NULL AND expires_at > ?", currentUser.ID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve sessions"})
+		return
+	}
+
+	response := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, models.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			ClientIP:   session.ClientIP,
+			ExpiresAt:  session.ExpiresAt,
+			LastUsedAt: session.LastUsedAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    session.FamilyID == currentSession.FamilyID,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": response})
+}
+
+// RevokeSession logs the current user out of one of their devices
+func (ac *AuthController) RevokeSession(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	sessionID := ctx.Param("sessionId")
+
+	if _, err := uuid.Parse(sessionID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid session ID format"})
+		return
+	}
+
+	var session models.Session
+	if err := ac.DB.First(&session, "id = ? AND user_id = ?", sessionID, currentUser.ID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Session not found"})
+		return
+	}
+
+	if err := revokeSessionFamily(ac.DB, session.FamilyID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to revoke session"})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// ChangePassword lets the current user set a new password after confirming the current one
+func (ac *AuthController) ChangePassword(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	currentSession := ctx.MustGet("currentSession").(models.Session)
+	var payload *models.ChangePasswordInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if payload.Password != payload.PasswordConfirm {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Passwords do not match"})
+		return
+	}
+
+	if err := utils.VerifyPassword(currentUser.Password, payload.CurrentPassword); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(payload.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	if err := ac.DB.WithContext(ctx).Model(&currentUser).Updates(map[string]interface{}{"password": hashedPassword, "updated_at": time.Now()}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update password"})
+		return
+	}
+	middleware.InvalidateUser(currentUser.ID)
+
+	// Keep the current device logged in and log out every other one
+	if err := ac.DB.Model(&models.Session{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", currentUser.ID, currentSession.FamilyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to revoke sessions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Password changed successfully"})
+}
+
+// ForgotPassword emails a single-use password reset link. It answers the same
+// way whether or not the email exists so accounts cannot be enumerated.
+func (ac *AuthController) ForgotPassword(ctx *gin.Context) {
+	var payload *models.ForgotPasswordInput
+	message := "If an account with that email exists, a password reset link has been sent"
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if !utils.MailEnabled(ac.Mailer) {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "message": "Password reset emails are unavailable, ask an administrator to reset your password"})
+		return
+	}
+
+	var user models.User
+	if err := ac.DB.First(&user, "email = ?", strings.ToLower(payload.Email)).Error; err != nil {
+		ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": message})
+		return
+	}
+
+	token, err := ac.createUserToken(user.ID, models.TokenPurposePasswordReset, ac.Config.PasswordResetTokenExpiresIn)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create password reset token"})
+		return
+	}
+
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", ac.Config.ClientOrigin, token)
+	body := fmt.Sprintf("Hello %s,\n\nUse the link below to reset your password. The link expires in %s and can only be used once.\n\n%s\n\nIf you did not request a password reset, you can ignore this email.", user.Name, ac.Config.PasswordResetTokenExpiresIn, resetURL)
+	if err := ac.Mailer.Send(user.Email, "Reset your password", body); err != nil {
+		log.Println("Failed to send password reset email:", err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": message})
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword
+func (ac *AuthController) ResetPassword(ctx *gin.Context) {
+	var payload *models.ResetPasswordInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if payload.Password != payload.PasswordConfirm {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Passwords do not match"})
+		return
+	}
+
+	userToken, err := ac.consumeUserToken(payload.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(payload.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	// The new password also lifts a lockout, the user proved they own the email
+	if err := ac.DB.Model(&models.User{}).Where("id = ?", userToken.UserID).
+		Updates(map[string]interface{}{
+			"password":             hashedPassword,
+			"failed_login_count":   0,
+			"locked_until":         nil,
+			"last_failed_login_at": nil,
+			"updated_at":           time.Now(),
+		}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update password"})
+		return
+	}
+
+	if err := revokeUserSessions(ac.DB, userToken.UserID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to revoke sessions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Password reset successfully"})
+}
+
+// VerifyEmail marks the account that requested the token as verified
+func (ac *AuthController) VerifyEmail(ctx *gin.Context) {
+	userToken, err := ac.consumeUserToken(ctx.Param("token"), models.TokenPurposeEmailVerification)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := ac.DB.Model(&models.User{}).Where("id = ?", userToken.UserID).
+		Updates(map[string]interface{}{"verified": true, "updated_at": time.Now()}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to verify email"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Email verified successfully"})
+}
+
+// FindInvitation shows who an invitation is for, so the invitee can see it before accepting
+func (ac *AuthController) FindInvitation(ctx *gin.Context) {
+	invitation, err := ac.findPendingInvitation(ctx.Param("token"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"email": invitation.Email, "role": invitation.Role, "expires_at": invitation.ExpiresAt}})
+}
+
+// AcceptInvitation creates the invited user with the role chosen by the admin.
+// The invitation link was delivered to the email address, so the account is verified.
+func (ac *AuthController) AcceptInvitation(ctx *gin.Context) {
+	var payload *models.AcceptInvitationInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if payload.Password != payload.PasswordConfirm {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Passwords do not match"})
+		return
+	}
+
+	invitation, err := ac.findPendingInvitation(payload.Token)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(payload.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	newUser := models.User{
+		Name:      payload.Name,
+		Email:     invitation.Email,
+		Password:  hashedPassword,
+		Role:      invitation.Role,
+		Verified:  true,
+		Photo:     payload.Photo,
+		Provider:  "local",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = ac.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		accepted := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.ID).
+			Update("accepted_at", now)
+		if accepted.Error != nil {
+			return accepted.Error
+		}
+		if accepted.RowsAffected == 0 {
+			return fmt.Errorf("invitation is no longer valid")
+		}
+
+		return tx.Create(&newUser).Error
+	})
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique") {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "User with that email already exists"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	userResponse := &models.UserResponse{
+		ID:        newUser.ID,
+		Name:      newUser.Name,
+		Email:     newUser.Email,
+		Photo:     newUser.Photo,
+		Role:      newUser.Role,
+		Provider:  newUser.Provider,
+		CreatedAt: newUser.CreatedAt,
+		UpdatedAt: newUser.UpdatedAt,
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": gin.H{"user": userResponse}})
+}
+
+// findPendingInvitation returns the invitation for a token that was neither
+// accepted, revoked nor expired
+func (ac *AuthController) findPendingInvitation(token string) (models.Invitation, error) {
+	var invitation models.Invitation
+	err := ac.DB.First(&invitation, "token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", utils.HashToken(token), time.Now()).Error
+	if err != nil {
+		return invitation, fmt.Errorf("invalid or expired invitation")
+	}
+	return invitation, nil
+}
+
+// createUserToken invalidates the user's unused tokens for the purpose and
+// returns a new one
+func (ac *AuthController) createUserToken(userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = ac.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: now.Add(ttl),
+			CreatedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeUserToken marks a valid token as used and returns it
+func (ac *AuthController) consumeUserToken(token string, purpose string) (models.UserToken, error) {
+	var userToken models.UserToken
+	if err := ac.DB.First(&userToken, "token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).Error; err != nil {
+		return userToken, fmt.Errorf("invalid or expired token")
+	}
+
+	if userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+		return userToken, fmt.Errorf("invalid or expired token")
+	}
+
+	result := ac.DB.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", userToken.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return userToken, fmt.Errorf("invalid or expired token")
+	}
+
+	return userToken, nil
+}
+
+// loginDelay returns how long a user has to wait after their last failed login
+func loginDelay(failedLoginCount int) time.Duration {
+	if failedLoginCount < loginDelayThreshold {
+		return 0
+	}
+
+	delay := time.Second << uint(failedLoginCount-loginDelayThreshold)
+	if delay > maxLoginDelay || delay <= 0 {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+// registerFailedLogin increments the user's failure counter and locks the
+// account every time it reaches a multiple of the lockout threshold
+func (ac *AuthController) registerFailedLogin(user *models.User) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"failed_login_count":   gorm.Expr("failed_login_count + 1"),
+		"last_failed_login_at": now,
+	}
+	if (user.FailedLoginCount+1)%lockoutThreshold == 0 {
+		updates["locked_until"] = now.Add(lockoutDuration)
+	}
+
+	if err := ac.DB.Model(user).Updates(updates).Error; err != nil {
+		log.Println("Failed to register failed login:", err)
+	}
+}
+
+// recordLoginAttempt writes an entry to the login history
+func (ac *AuthController) recordLoginAttempt(ctx *gin.Context, email string, userID *uuid.UUID, success bool, reason string) {
+	attempt := models.LoginAttempt{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Email:     email,
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+
+	if err := ac.DB.Create(&attempt).Error; err != nil {
+		log.Println("Failed to record login attempt:", err)
+	}
+}
+
+// createSession stores a new refresh token session in the given family and
+// returns it together with the signed access and refresh tokens
+func (ac *AuthController) createSession(ctx *gin.Context, user models.User, familyID uuid.UUID) (models.Session, string, string, error) {
+	userID := user.ID
+	accessClaims := map[string]interface{}{"sid": familyID.String(), "role": user.Role, "tenant": user.Tenant()}
+
+	access_token, err := ac.Tokens.CreateAccessToken(ac.Config.AccessTokenExpiresIn, userID, accessClaims)
+	if err != nil {
+		return models.Session{}, "", "", err
+	}
+
+	refresh_token, err := ac.Tokens.CreateRefreshToken(ac.Config.RefreshTokenExpiresIn, userID, map[string]interface{}{"sid": familyID.String()})
+	if err != nil {
+		return models.Session{}, "", "", err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		FamilyID:   familyID,
+		TokenHash:  utils.HashToken(refresh_token),
+		UserAgent:  ctx.Request.UserAgent(),
+		ClientIP:   ctx.ClientIP(),
+		ExpiresAt:  now.Add(ac.Config.RefreshTokenExpiresIn),
+		LastUsedAt: now,
+		CreatedAt:  now,
+	}
+
+	if err := ac.DB.Create(&session).Error; err != nil {
+		return models.Session{}, "", "", fmt.Errorf("could not create session: %w", err)
+	}
+	middleware.InvalidateSessionFamily(familyID)
+
+	return session, access_token, refresh_token, nil
+}
+
+// revokeSessionFamily revokes every refresh token issued to one device
+func revokeSessionFamily(db *gorm.DB, familyID uuid.UUID) error {
+	defer middleware.InvalidateSessionFamily(familyID)
+	return db.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeUserSessions revokes every refresh token issued to the user
+func revokeUserSessions(db *gorm.DB, userID uuid.UUID) error {
+	defer middleware.InvalidateUser(userID)
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
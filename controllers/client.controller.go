@@ -29,16 +29,17 @@ func (cc *ClientController) CreateClient(ctx *gin.Context) {
 
 	now := time.Now()
 	newClient := models.Client{
-		ID:        uuid.New(),
-		Name:      payload.Name,
-		Phone:     payload.Phone,
-		Address:   payload.Address,
-		Note:      payload.Note,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:          uuid.New(),
+		Name:        payload.Name,
+		Phone:       payload.Phone,
+		Address:     payload.Address,
+		Note:        payload.Note,
+		RequiresPOD: payload.RequiresPOD,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
-	result := cc.DB.Create(&newClient)
+	result := cc.DB.WithContext(ctx).Create(&newClient)
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": result.Error.Error()})
 		return
@@ -84,14 +85,15 @@ func (cc *ClientController) UpdateClient(ctx *gin.Context) {
 	}
 
 	updateData := models.UpdateClient{
-		Name:      payload.Name,
-		Phone:     payload.Phone,
-		Address:   payload.Address,
-		Note:      payload.Note,
-		UpdatedAt: time.Now(),
+		Name:        payload.Name,
+		Phone:       payload.Phone,
+		Address:     payload.Address,
+		Note:        payload.Note,
+		RequiresPOD: payload.RequiresPOD,
+		UpdatedAt:   time.Now(),
 	}
 
-	cc.DB.Model(&client).Updates(updateData)
+	cc.DB.WithContext(ctx).Model(&client).Updates(updateData)
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": client})
 }
@@ -99,7 +101,7 @@ func (cc *ClientController) UpdateClient(ctx *gin.Context) {
 func (cc *ClientController) DeleteClient(ctx *gin.Context) {
 	clientID := ctx.Param("clientId")
 
-	result := cc.DB.Delete(&models.Client{}, "id = ?", clientID)
+	result := cc.DB.WithContext(ctx).Delete(&models.Client{}, "id = ?", clientID)
 	if result.RowsAffected == 0 {
 		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No client with that ID exists"})
 		return
@@ -38,10 +38,10 @@ func (cc *ContractorController) CreateContractor(ctx *gin.Context) {
 	}
 
 	if payload.Name == "T&T" || payload.Name == "T & T" {
-		newContractor.Type = "internal"
+		newContractor.Type = models.ContractorTypeInternal
 	}
 
-	result := cc.DB.Create(&newContractor)
+	result := cc.DB.WithContext(ctx).Create(&newContractor)
 	if result.Error != nil {
 		if strings.Contains(result.Error.Error(), "duplicate key") {
 			ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "Contractor with that name already exists"})
@@ -80,7 +80,7 @@ func (cc *ContractorController) UpdateContractor(ctx *gin.Context) {
 		UpdatedAt: now,
 	}
 
-	cc.DB.Model(&updatedContractor).Updates(contractorToUpdate)
+	cc.DB.WithContext(ctx).Model(&updatedContractor).Updates(contractorToUpdate)
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": updatedContractor})
 }
@@ -121,7 +121,7 @@ func (cc *ContractorController) FindContractors(ctx *gin.Context) {
 func (cc *ContractorController) DeleteContractor(ctx *gin.Context) {
 	contractorId := ctx.Param("contractorId")
 
-	result := cc.DB.Delete(&models.Contractor{}, "id = ?", contractorId)
+	result := cc.DB.WithContext(ctx).Delete(&models.Contractor{}, "id = ?", contractorId)
 	if result.Error != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Contractor with that ID does not exist"})
 		return
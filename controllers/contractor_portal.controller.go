@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+// ContractorPortalController serves the /me/contractor routes. Every query is
+// scoped to the contractor linked to the current user by RequireContractor.
+type ContractorPortalController struct {
+	DB *gorm.DB
+}
+
+func NewContractorPortalController(DB *gorm.DB) ContractorPortalController {
+	return ContractorPortalController{DB}
+}
+
+// GetProfile returns the contractor the user works for
+func (cc *ContractorPortalController) GetProfile(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	var contractor models.Contractor
+	if err := cc.DB.First(&contractor, "id = ?", contractorID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Contractor not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": contractor})
+}
+
+// GetOrders lists the contractor's orders, newest first. The month and year
+// queries select the month the orders took place in.
+func (cc *ContractorPortalController) GetOrders(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 50, 200)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	query := cc.DB.Model(&models.Order{}).Where("contractor_id = ?", contractorID)
+
+	month, year, ok := periodQuery(ctx)
+	if !ok {
+		return
+	}
+	if month > 0 {
+		query = query.Where("EXTRACT(MONTH from order_time) = ? AND EXTRACT(YEAR FROM order_time) = ?", month, year)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var orders []models.Order
+	if err := query.Preload("Driver").Preload("Truck").Preload("Client").
+		Order("order_time DESC").Limit(page.Limit).Offset(page.Offset).
+		Find(&orders).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve orders"})
+		return
+	}
+
+	for i := range orders {
+		orders[i].HideClientPricing()
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(orders), "total": total, "data": orders})
+}
+
+// GetOrder returns one of the contractor's orders
+func (cc *ContractorPortalController) GetOrder(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	id := ctx.Param("orderId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order ID format"})
+		return
+	}
+
+	var order models.Order
+	if err := cc.DB.Preload("Driver").Preload("Truck").Preload("Client").Preload("Stops", orderStopsInSequence).
+		First(&order, "id = ? AND contractor_id = ?", id, contractorID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Order not found"})
+		return
+	}
+
+	order.HideClientPricing()
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": order})
+}
+
+// GetPricings lists the pricing tables we pay the contractor by, newest first
+func (cc *ContractorPortalController) GetPricings(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	var pricings []models.Pricing
+	if err := cc.DB.Preload("PriceDetails").
+		Where("owner_id = ? AND owner_type = ?", contractorID, models.PricingOwnerContractor).
+		Order("created_at DESC").Find(&pricings).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve pricings"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(pricings), "data": pricings})
+}
+
+// GetTrucks lists the contractor's trucks
+func (cc *ContractorPortalController) GetTrucks(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	var trucks []models.Truck
+	if err := cc.DB.Where("contractor_id = ?", contractorID).Order("license_plate").Find(&trucks).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve trucks"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(trucks), "data": trucks})
+}
+
+// GetDrivers lists the contractor's drivers
+func (cc *ContractorPortalController) GetDrivers(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	var drivers []models.Driver
+	if err := cc.DB.Where("contractor_id = ?", contractorID).Order("full_name").Find(&drivers).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve drivers"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(drivers), "data": drivers})
+}
+
+// GetPayslips lists the contractor-level payslips, the ones not tied to a
+// single driver, newest month first. The month and year queries select a
+// single month.
+func (cc *ContractorPortalController) GetPayslips(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	query := cc.DB.Where("contractor_id = ? AND driver_id IS NULL AND submitted = ?", contractorID, true)
+
+	month, year, ok := periodQuery(ctx)
+	if !ok {
+		return
+	}
+	if month > 0 {
+		query = query.Where("month = ? AND year = ?", month, year)
+	}
+
+	var payslips []models.Payslip
+	if err := query.Order("year DESC, month DESC").Find(&payslips).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve payslips"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(payslips), "data": payslips})
+}
+
+// GetPayslip returns one of the contractor-level payslips
+func (cc *ContractorPortalController) GetPayslip(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	id := ctx.Param("payslipId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid payslip ID format"})
+		return
+	}
+
+	var payslip models.Payslip
+	if err := cc.DB.First(&payslip, "id = ? AND contractor_id = ? AND driver_id IS NULL AND submitted = ?", id, contractorID, true).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Payslip not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": payslip})
+}
+
+// GetSettlements lists the contractor's answers to past settlements, newest
+// month first
+func (cc *ContractorPortalController) GetSettlements(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	var settlements []models.Settlement
+	if err := cc.DB.Where("contractor_id = ?", contractorID).
+		Order("year DESC, month DESC").Find(&settlements).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve settlements"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(settlements), "data": settlements})
+}
+
+// GetSettlement returns the settlement of a month with the figures as they
+// are now. Once answered, the figures the contractor answered to are kept in
+// the settlement, so a difference shows the orders changed afterwards.
+func (cc *ContractorPortalController) GetSettlement(ctx *gin.Context) {
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	month, year, ok := settlementPeriod(ctx)
+	if !ok {
+		return
+	}
+
+	settlement, err := cc.findSettlement(contractorID, month, year)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve settlement"})
+		return
+	}
+
+	tripCount, amount, err := cc.settlementFigures(contractorID, month, year)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to calculate settlement"})
+		return
+	}
+	if settlement.Status == models.SettlementPending {
+		settlement.TripCount = tripCount
+		settlement.Amount = amount
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{
+		"settlement": settlement,
+		"current":    gin.H{"trip_count": tripCount, "amount": amount},
+	}})
+}
+
+// ConfirmSettlement records that the contractor agrees with the trips and
+// amount of a month
+func (cc *ContractorPortalController) ConfirmSettlement(ctx *gin.Context) {
+	cc.answerSettlement(ctx, models.SettlementConfirmed, "")
+}
+
+// DisputeSettlement records that the contractor disagrees with the trips or
+// amount of a month, and why
+func (cc *ContractorPortalController) DisputeSettlement(ctx *gin.Context) {
+	var payload models.DisputeSettlementInput
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	cc.answerSettlement(ctx, models.SettlementDisputed, payload.Reason)
+}
+
+func (cc *ContractorPortalController) answerSettlement(ctx *gin.Context, status string, reason string) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	contractorID := ctx.MustGet("currentContractorID").(uuid.UUID)
+
+	month, year, ok := settlementPeriod(ctx)
+	if !ok {
+		return
+	}
+
+	// The figures of a month keep changing until it is over
+	if !time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local).AddDate(0, 1, 0).Before(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "The month is not over yet"})
+		return
+	}
+
+	settlement, err := cc.findSettlement(contractorID, month, year)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve settlement"})
+		return
+	}
+	if settlement.Status == models.SettlementConfirmed {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "The settlement is already confirmed"})
+		return
+	}
+
+	tripCount, amount, err := cc.settlementFigures(contractorID, month, year)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to calculate settlement"})
+		return
+	}
+
+	now := time.Now()
+	settlement.TripCount = tripCount
+	settlement.Amount = amount
+	settlement.Status = status
+	settlement.DisputeReason = reason
+	settlement.RespondedByID = &currentUser.ID
+	settlement.RespondedAt = &now
+	settlement.UpdatedAt = now
+
+	if settlement.ID == uuid.Nil {
+		settlement.ID = uuid.New()
+		settlement.CreatedAt = now
+		err = cc.DB.WithContext(ctx).Create(&settlement).Error
+	} else {
+		err = cc.DB.WithContext(ctx).Save(&settlement).Error
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to save settlement"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": settlement})
+}
+
+// findSettlement returns the stored settlement of the month, or a pending one
+// that has not been saved yet
+func (cc *ContractorPortalController) findSettlement(contractorID uuid.UUID, month int, year int) (models.Settlement, error) {
+	var settlement models.Settlement
+	err := cc.DB.First(&settlement, "contractor_id = ? AND year = ? AND month = ?", contractorID, year, month).Error
+	if err == gorm.ErrRecordNotFound {
+		return models.Settlement{ContractorID: contractorID, Year: year, Month: month, Status: models.SettlementPending}, nil
+	}
+	return settlement, err
+}
+
+// settlementFigures adds up the trips and the contractor prices of the
+// contractor's orders in the month, cancelled ones excluded
+func (cc *ContractorPortalController) settlementFigures(contractorID uuid.UUID, month int, year int) (int64, float64, error) {
+	var figures struct {
+		TripCount int64
+		Amount    float64
+	}
+	err := cc.DB.Model(&models.Order{}).
+		Select("COALESCE(SUM(trip_count), 0) AS trip_count, COALESCE(SUM(price_for_contractor), 0) AS amount").
+		Where("contractor_id = ? AND status <> ?", contractorID, models.OrderStatusCancelled).
+		Where("EXTRACT(MONTH from order_time) = ? AND EXTRACT(YEAR FROM order_time) = ?", month, year).
+		Scan(&figures).Error
+	return figures.TripCount, figures.Amount, err
+}
+
+// settlementPeriod reads the year and month path parameters
+func settlementPeriod(ctx *gin.Context) (int, int, bool) {
+	month, err := strconv.Atoi(ctx.Param("month"))
+	if err != nil || month < 1 || month > 12 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid month"})
+		return 0, 0, false
+	}
+
+	year, err := strconv.Atoi(ctx.Param("year"))
+	if err != nil || year < 2018 || year > 2100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid year"})
+		return 0, 0, false
+	}
+
+	return month, year, true
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
 	"github.com/wpcodevo/golang-gorm-postgres/models"
 	"gorm.io/gorm"
 )
@@ -26,6 +27,11 @@ func (dc *DriverController) CreateDriver(ctx *gin.Context) {
 		return
 	}
 
+	// The fixed salary is only set by roles with access to payroll data
+	if !middleware.HasPermission(ctx, models.PermSalaryWrite) {
+		payload.FixedSalary = nil
+	}
+
 	// Convert contractor string to UUID if present
 	var contractorID uuid.UUID
 	if payload.ContractorID != uuid.Nil {
@@ -56,12 +62,16 @@ func (dc *DriverController) CreateDriver(ctx *gin.Context) {
 		UpdatedAt:     now,
 	}
 
-	result := dc.DB.Create(&newDriver)
+	result := dc.DB.WithContext(ctx).Create(&newDriver)
 	if result.Error != nil {
 		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": result.Error.Error()})
 		return
 	}
 
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		newDriver.HideSalary()
+	}
+
 	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": newDriver})
 }
 
@@ -75,6 +85,10 @@ func (dc *DriverController) FindDriverById(ctx *gin.Context) {
 		return
 	}
 
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		driver.HideSalary()
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": driver})
 }
 
@@ -86,6 +100,12 @@ func (dc *DriverController) FindDrivers(ctx *gin.Context) {
 		return
 	}
 
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		for i := range drivers {
+			drivers[i].HideSalary()
+		}
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(drivers), "data": drivers})
 }
 
@@ -133,7 +153,7 @@ func (dc *DriverController) UpdateDriver(ctx *gin.Context) {
 	if payload.ContractorID != uuid.Nil {
 		driverToUpdate.ContractorID = payload.ContractorID
 	}
-	if payload.FixedSalary != nil {
+	if payload.FixedSalary != nil && middleware.HasPermission(ctx, models.PermSalaryWrite) {
 		driverToUpdate.FixedSalary = payload.FixedSalary
 	}
 	if payload.Note != "" {
@@ -141,19 +161,23 @@ func (dc *DriverController) UpdateDriver(ctx *gin.Context) {
 	}
 
 	// Update the driver in the database
-	result = dc.DB.Model(&driverToUpdate).Updates(driverToUpdate)
+	result = dc.DB.WithContext(ctx).Model(&driverToUpdate).Updates(driverToUpdate)
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": result.Error.Error()})
 		return
 	}
 
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		driverToUpdate.HideSalary()
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": driverToUpdate})
 }
 
 func (dc *DriverController) DeleteDriver(ctx *gin.Context) {
 	driverId := ctx.Param("driverId")
 
-	result := dc.DB.Delete(&models.Driver{}, "id = ?", driverId)
+	result := dc.DB.WithContext(ctx).Delete(&models.Driver{}, "id = ?", driverId)
 
 	if result.Error != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No driver with that ID exists"})
@@ -190,7 +214,7 @@ func (tc *DriverController) DeleteDrivers(ctx *gin.Context) {
 		}
 
 		// Try to delete the driver
-		result := tc.DB.Where("id = ?", id).Delete(&models.Driver{})
+		result := tc.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.Driver{})
 		if result.Error != nil {
 			failedIDs = append(failedIDs, id)
 			continue // Skip errors and continue with other IDs
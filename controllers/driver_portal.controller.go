@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+// DriverPortalController serves the /me/driver routes. Every query is scoped
+// to the driver record linked to the current user by RequireDriver.
+type DriverPortalController struct {
+	DB *gorm.DB
+}
+
+func NewDriverPortalController(DB *gorm.DB) DriverPortalController {
+	return DriverPortalController{DB}
+}
+
+// GetProfile returns the driver's own record and how long their license is valid
+func (dc *DriverPortalController) GetProfile(ctx *gin.Context) {
+	driverID := ctx.MustGet("currentDriverID").(uuid.UUID)
+
+	var driver models.Driver
+	if err := dc.DB.First(&driver, "id = ?", driverID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Driver not found"})
+		return
+	}
+
+	response := gin.H{"driver": driver, "license_expiry": driver.LicenseExpiry}
+	if !driver.LicenseExpiry.IsZero() {
+		daysLeft := int(math.Floor(time.Until(driver.LicenseExpiry).Hours() / 24))
+		response["license_expires_in_days"] = daysLeft
+		response["license_expired"] = daysLeft < 0
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": response})
+}
+
+// GetOrders lists the driver's own orders, newest first. The month and year
+// queries select the month the orders took place in.
+func (dc *DriverPortalController) GetOrders(ctx *gin.Context) {
+	driverID := ctx.MustGet("currentDriverID").(uuid.UUID)
+
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 50, 200)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	query := dc.DB.Model(&models.Order{}).Where("driver_id = ?", driverID)
+
+	month, year, ok := periodQuery(ctx)
+	if !ok {
+		return
+	}
+	if month > 0 {
+		query = query.Where("EXTRACT(MONTH from order_time) = ? AND EXTRACT(YEAR FROM order_time) = ?", month, year)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var orders []models.Order
+	if err := query.Preload("Truck").Preload("Client").
+		Order("order_time DESC").Limit(page.Limit).Offset(page.Offset).
+		Find(&orders).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve orders"})
+		return
+	}
+
+	for i := range orders {
+		orders[i].HidePricing()
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(orders), "total": total, "data": orders})
+}
+
+// GetOrder returns one of the driver's own orders
+func (dc *DriverPortalController) GetOrder(ctx *gin.Context) {
+	driverID := ctx.MustGet("currentDriverID").(uuid.UUID)
+
+	id := ctx.Param("orderId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order ID format"})
+		return
+	}
+
+	var order models.Order
+	if err := dc.DB.Preload("Truck").Preload("Client").
+		Preload("Stops", orderStopsInSequence).
+		Preload("PODs", orderPODsInDeliveryOrder).
+		Preload("PODs.Files").
+		First(&order, "id = ? AND driver_id = ?", id, driverID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Order not found"})
+		return
+	}
+
+	order.HidePricing()
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": order})
+}
+
+// GetPayslips lists the driver's submitted payslips, newest month first.
+// The month and year queries select a single month.
+func (dc *DriverPortalController) GetPayslips(ctx *gin.Context) {
+	driverID := ctx.MustGet("currentDriverID").(uuid.UUID)
+
+	query := dc.DB.Where("driver_id = ? AND submitted = ?", driverID, true)
+
+	month, year, ok := periodQuery(ctx)
+	if !ok {
+		return
+	}
+	if month > 0 {
+		query = query.Where("month = ? AND year = ?", month, year)
+	}
+
+	var payslips []models.Payslip
+	if err := query.Order("year DESC, month DESC").Find(&payslips).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve payslips"})
+		return
+	}
+
+	for i := range payslips {
+		payslips[i].HidePricing()
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(payslips), "data": payslips})
+}
+
+// GetPayslip returns one of the driver's submitted payslips
+func (dc *DriverPortalController) GetPayslip(ctx *gin.Context) {
+	driverID := ctx.MustGet("currentDriverID").(uuid.UUID)
+
+	id := ctx.Param("payslipId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid payslip ID format"})
+		return
+	}
+
+	var payslip models.Payslip
+	if err := dc.DB.First(&payslip, "id = ? AND driver_id = ? AND submitted = ?", id, driverID, true).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Payslip not found"})
+		return
+	}
+
+	payslip.HidePricing()
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": payslip})
+}
+
+// periodQuery reads the optional month and year queries. Both must be
+// given together; month is 0 when neither is.
+func periodQuery(ctx *gin.Context) (int, int, bool) {
+	month := ctx.Query("month")
+	year := ctx.Query("year")
+	if month == "" && year == "" {
+		return 0, 0, true
+	}
+
+	monthInt, err := strconv.Atoi(month)
+	if err != nil || monthInt < 1 || monthInt > 12 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid month"})
+		return 0, 0, false
+	}
+
+	yearInt, err := strconv.Atoi(year)
+	if err != nil || yearInt < 2018 || yearInt > 2100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid year"})
+		return 0, 0, false
+	}
+
+	return monthInt, yearInt, true
+}
@@ -1,12 +1,18 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type FileController struct {
@@ -55,6 +61,87 @@ func (fc *FileController) UploadFile(ctx *gin.Context) {
 	})
 }
 
+// errFileType is returned by SaveFile for a file of a type that is not allowed
+var errFileType = errors.New("file type is not allowed")
+
+// SaveFile stores an uploaded file under a new unique name, so uploads with
+// the same name do not overwrite each other. The extension comes from the
+// detected content type, not the client's file name, so a file is never
+// served as a type it was not checked as. It
+// returns the stored name, which DownloadFile serves, and the detected
+// content type. When allowedTypes are given the content type must start with
+// one of them.
+func (fc *FileController) SaveFile(fileHeader *multipart.FileHeader, allowedTypes ...string) (string, string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	// Sniff the content type from the start of the file, not its name
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", "", err
+	}
+	contentType := http.DetectContentType(head[:n])
+	if len(allowedTypes) > 0 {
+		allowed := false
+		for _, prefix := range allowedTypes {
+			allowed = allowed || strings.HasPrefix(contentType, prefix)
+		}
+		if !allowed {
+			return "", "", errFileType
+		}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(fc.BasePath, os.ModePerm); err != nil {
+		return "", "", err
+	}
+
+	name := uuid.New().String() + fileExtension(contentType)
+	out, err := os.Create(filepath.Join(fc.BasePath, name))
+	if err != nil {
+		return "", "", err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, file); err != nil {
+		os.Remove(out.Name())
+		return "", "", err
+	}
+	return name, contentType, nil
+}
+
+// fileExtension returns the extension to store a file of the content type
+// under, or none when the type has no known extension
+func fileExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	// The system table lists .jfif and .jpe before .jpg
+	if mediaType == "image/jpeg" {
+		return ".jpg"
+	}
+	extensions, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(extensions) == 0 {
+		return ""
+	}
+	return extensions[0]
+}
+
+// RemoveFile deletes a stored file, a missing file is not an error
+func (fc *FileController) RemoveFile(name string) error {
+	if err := os.Remove(filepath.Join(fc.BasePath, filepath.Base(name))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // DownloadFile serves a file to the client
 func (fc *FileController) DownloadFile(ctx *gin.Context) {
 	fileName := ctx.Param("fileName")
@@ -66,7 +153,9 @@ func (fc *FileController) DownloadFile(ctx *gin.Context) {
 		return
 	}
 
-	// Serve the file
+	// Serve the file, browsers must not guess a type other than the one
+	// its extension gives
+	ctx.Header("X-Content-Type-Options", "nosniff")
 	ctx.File(filePath)
 }
 
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFileExtension(t *testing.T) {
+	gif := []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;<html><script>alert(1)</script></html>")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF")
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{name: "gif polyglot", content: gif, want: ".gif"},
+		{name: "png", content: png, want: ".png"},
+		{name: "jpeg", content: jpeg, want: ".jpg"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := fileExtension(http.DetectContentType(test.content)); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/initializers"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+type InvitationController struct {
+	DB     *gorm.DB
+	Mailer utils.Mailer
+	Config *initializers.Config
+}
+
+func NewInvitationController(DB *gorm.DB, Mailer utils.Mailer, Config *initializers.Config) InvitationController {
+	return InvitationController{DB, Mailer, Config}
+}
+
+// CreateInvitation emails an invitation link to a new user with a pre-assigned
+// role. Pending invitations for the same email are revoked. When email is not
+// configured the link is returned to the admin instead.
+func (ic *InvitationController) CreateInvitation(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload models.CreateInvitationInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if !models.IsValidRole(payload.Role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid role"})
+		return
+	}
+
+	email := strings.ToLower(payload.Email)
+
+	var existing int64
+	ic.DB.Model(&models.User{}).Where("email = ?", email).Count(&existing)
+	if existing > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "User with that email already exists"})
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	invitation := models.Invitation{
+		ID:          uuid.New(),
+		Email:       email,
+		Role:        payload.Role,
+		TokenHash:   utils.HashToken(token),
+		InvitedByID: currentUser.ID,
+		ExpiresAt:   now.Add(ic.Config.InvitationExpiresIn),
+		CreatedAt:   now,
+	}
+
+	err = ic.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Invitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	inviteURL := fmt.Sprintf("%s/accept-invitation?token=%s", ic.Config.ClientOrigin, token)
+
+	// Without email the admin passes the link on to the invitee
+	if !utils.MailEnabled(ic.Mailer) {
+		ctx.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Email is not configured on this server, send the invitation link to the invitee yourself", "data": invitation, "invitation_url": inviteURL})
+		return
+	}
+
+	body := fmt.Sprintf("Hello,\n\n%s has invited you to join as %s. Open the link below to set your password. The invitation expires in %s.\n\n%s", currentUser.Name, invitation.Role, ic.Config.InvitationExpiresIn, inviteURL)
+	if err := ic.Mailer.Send(invitation.Email, "You have been invited", body); err != nil {
+		log.Println("Failed to send invitation email:", err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": "Invitation created but the email could not be sent"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": invitation})
+}
+
+// FindInvitations lists invitations, optionally only those still pending
+func (ic *InvitationController) FindInvitations(ctx *gin.Context) {
+	var page = ctx.DefaultQuery("page", "1")
+	var limit = ctx.DefaultQuery("limit", "200")
+
+	intPage, _ := strconv.Atoi(page)
+	intLimit, _ := strconv.Atoi(limit)
+	offset := (intPage - 1) * intLimit
+
+	query := ic.DB.Preload("InvitedBy")
+	if ctx.Query("status") == "pending" {
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	var invitations []models.Invitation
+	if err := query.Order("created_at DESC").Limit(intLimit).Offset(offset).Find(&invitations).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(invitations), "data": invitations})
+}
+
+// RevokeInvitation cancels an invitation that has not been accepted yet
+func (ic *InvitationController) RevokeInvitation(ctx *gin.Context) {
+	invitationID := ctx.Param("invitationId")
+
+	if _, err := uuid.Parse(invitationID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid invitation ID format"})
+		return
+	}
+
+	result := ic.DB.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No pending invitation with that ID exists"})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+)
+
+type JWKSController struct {
+	Tokens *utils.TokenManager
+}
+
+func NewJWKSController(Tokens *utils.TokenManager) JWKSController {
+	return JWKSController{Tokens}
+}
+
+// GetJWKS publishes the public keys that verify access tokens, so the
+// frontend and partner services can check tokens without calling the API
+func (jc *JWKSController) GetJWKS(ctx *gin.Context) {
+	publicKeys := jc.Tokens.PublicKeys(utils.KeyUseAccess)
+
+	ids := make([]string, 0, len(publicKeys))
+	for id := range publicKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	keys := make([]utils.JSONWebKey, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, utils.NewJSONWebKey(id, publicKeys[id]))
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{"keys": keys})
+}
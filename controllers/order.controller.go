@@ -1,12 +1,19 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"net/http"
-	"strconv"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
 	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
 	"gorm.io/gorm"
 )
 
@@ -26,65 +33,286 @@ func (ctrl *OrderController) CreateOrder(ctx *gin.Context) {
 		return
 	}
 
+	// Prices can only be set by roles with access to pricing data, driver pay
+	// by roles with access to payroll data
+	if !middleware.HasPermission(ctx, models.PermPricingWrite) {
+		newOrder.HidePricing()
+	}
+	if !middleware.HasPermission(ctx, models.PermSalaryWrite) {
+		newOrder.HideSalary()
+	}
+
+	if err := newOrder.ApplyStops(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if err := newOrder.ApplyEndTime(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if !applyTotalSalary(ctx, &newOrder, nil) {
+		return
+	}
+
+	pricing, err := ctrl.priceOrder(&newOrder)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to look up the order prices"})
+		return
+	}
+
 	newOrder.ID = uuid.New() // Generate a new UUID for the order
-	if err := ctrl.DB.Create(&newOrder).Error; err != nil {
+	newOrder.Status = models.OrderStatusDraft
+
+	warnings, ok := ctrl.checkTruckLoad(ctx, &newOrder)
+	if !ok {
+		return
+	}
+	conflicts, ok := ctrl.checkAssignment(ctx, &newOrder)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newOrder).Error; err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			if err := tx.Create(newAssignmentOverrides(ctx, newOrder.ID, conflicts)).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(newOrderStatusHistory(ctx, newOrder.ID, "", newOrder.Status, "")).Error
+	}); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": newOrder})
+	if !middleware.HasPermission(ctx, models.PermPricingRead) {
+		newOrder.HidePricing()
+	}
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		newOrder.HideSalary()
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": newOrder, "pricing": pricing, "warnings": warnings})
+}
+
+// orderSortFields are the fields GetOrders can sort by
+var orderSortFields = map[string]string{
+	"order_time":        "order_time",
+	"created_at":        "created_at",
+	"updated_at":        "updated_at",
+	"status":            "status",
+	"order_type":        "order_type",
+	"pickup_province":   "pickup_province",
+	"delivery_province": "delivery_province",
+	"trip_count":        "trip_count",
+}
+
+// orderSalarySortFields can only be sorted by with access to payroll data
+var orderSalarySortFields = map[string]string{
+	"total_salary": "total_salary",
+}
+
+// orderPriceSortFields can only be sorted by with access to pricing data
+var orderPriceSortFields = map[string]string{
+	"price_from_client":    "price_from_client",
+	"price_for_contractor": "price_for_contractor",
+}
+
+// orderSumColumns are the money fields GetOrders adds up over every order
+// that matches the filters, not only the current page, mapped to their
+// column. Cancelled orders are listed but not added up.
+var orderSumColumns = map[string]string{
+	"trip_count":           "trip_count",
+	"trip_salary":          "trip_salary",
+	"daily_salary":         "daily_salary",
+	"point_salary":         "point_salary",
+	"recovery_fee":         "refund_fee",
+	"loading_salary":       "loading_salary",
+	"meal_fee":             "meal_fee",
+	"standby_fee":          "standby_fee",
+	"parking_fee":          "parking_fee",
+	"other_salary":         "other_salary",
+	"outside_oil_fee":      "outsite_oil_fee",
+	"oil_fee":              "oil_fee",
+	"charge_fee":           "charge_fee",
+	"total_salary":         "total_salary",
+	"price_from_client":    "price_from_client",
+	"price_for_contractor": "price_for_contractor",
+}
+
+// orderSalaryColumns are the driver pay fields, only shown with access to
+// payroll data, mapped to their column
+var orderSalaryColumns = map[string]string{
+	"trip_salary":     "trip_salary",
+	"daily_salary":    "daily_salary",
+	"point_salary":    "point_salary",
+	"recovery_fee":    "refund_fee",
+	"loading_salary":  "loading_salary",
+	"meal_fee":        "meal_fee",
+	"standby_fee":     "standby_fee",
+	"parking_fee":     "parking_fee",
+	"other_salary":    "other_salary",
+	"outside_oil_fee": "outsite_oil_fee",
+	"oil_fee":         "oil_fee",
+	"charge_fee":      "charge_fee",
+	"total_salary":    "total_salary",
+}
+
+// orderListQuery applies the filters shared by GetOrders and ExportOrders:
+// from and to (order_time, YYYY-MM-DD or RFC 3339), month and year,
+// driver_id, contractor_id, client_id, truck_id, order_type, status (comma
+// separated), pickup_province, delivery_province and q, a text search over
+// the notes. It writes the error response itself.
+func (ctrl *OrderController) orderListQuery(ctx *gin.Context) (*gorm.DB, bool) {
+	filter := utils.NewQueryFilter(ctrl.DB.Model(&models.Order{}))
+
+	// month and year select a whole month of order_time
+	month, year, ok := periodQuery(ctx)
+	if !ok {
+		return nil, false
+	}
+	if month > 0 {
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+		filter.Where("order_time >= ? AND order_time < ?", start, start.AddDate(0, 1, 0))
+	}
+
+	base, err := filter.
+		TimeRange("order_time", ctx.Query("from"), ctx.Query("to")).
+		UUID("driver_id", ctx.Query("driver_id"), "driver_id").
+		UUID("contractor_id", ctx.Query("contractor_id"), "contractor_id").
+		UUID("client_id", ctx.Query("client_id"), "client_id").
+		UUID("truck_id", ctx.Query("truck_id"), "truck_id").
+		OneOf("order_type", ctx.Query("order_type"), "order_type", func(orderType string) bool {
+			return orderType == models.OrderTypeInternal || orderType == models.OrderTypeExternal
+		}).
+		OneOf("status", ctx.Query("status"), "status", models.IsValidOrderStatus).
+		EqualsFold("pickup_province", ctx.Query("pickup_province")).
+		EqualsFold("delivery_province", ctx.Query("delivery_province")).
+		Search(ctx.Query("q"), "notes").
+		Query()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return nil, false
+	}
+	return base, true
+}
+
+// orderListSort reads the sort query value, price fields are only allowed
+// with access to pricing data
+func orderListSort(ctx *gin.Context, canReadPricing bool, canReadSalary bool) (string, bool) {
+	sortFields := make(map[string]string)
+	for field, column := range orderSortFields {
+		sortFields[field] = column
+	}
+	if canReadPricing {
+		for field, column := range orderPriceSortFields {
+			sortFields[field] = column
+		}
+	}
+	if canReadSalary {
+		for field, column := range orderSalarySortFields {
+			sortFields[field] = column
+		}
+	}
+	order, err := utils.ParseSort(ctx.Query("sort"), sortFields, "-order_time", "id")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return "", false
+	}
+	return order, true
 }
 
+// GetOrders lists orders, by default the latest order_time first.
+//
+// Filters: see orderListQuery. Paging: page and limit. Sorting: sort, such
+// as "-order_time,trip_count". The response also holds the sums of the
+// money columns over every matching order that was not cancelled.
 func (ctrl *OrderController) GetOrders(ctx *gin.Context) {
-	month := ctx.Query("month")
-	year := ctx.Query("year")
-	driverId := ctx.Query("driver_id")
-	contractorId := ctx.Query("contractor_id")
+	canReadPricing := middleware.HasPermission(ctx, models.PermPricingRead)
+	canReadSalary := middleware.HasPermission(ctx, models.PermSalaryRead)
 
-	// validate the parameters
-	if month == "" || year == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Month and Year are required"})
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 200, 1000)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	// convert month and year to integer
+	order, ok := orderListSort(ctx, canReadPricing, canReadSalary)
+	if !ok {
+		return
+	}
 
-	monthInt, err := strconv.Atoi(month)
-	if err != nil || monthInt < 1 || monthInt > 12 {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": "Invalid month"})
+	base, ok := ctrl.orderListQuery(ctx)
+	if !ok {
 		return
 	}
 
-	yearInt, err := strconv.Atoi(year)
-	if err != nil || yearInt < 2018 || yearInt > 2100 {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": "Invalid year"})
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve orders"})
 		return
 	}
 
-	// query database with specific month and year
+	sumSelects := make([]string, 0, len(orderSumColumns))
+	for field, column := range orderSumColumns {
+		sumSelects = append(sumSelects, "COALESCE(SUM("+column+"), 0) AS "+field)
+	}
+	sort.Strings(sumSelects)
+	sums := map[string]interface{}{}
+	if err := base.Session(&gorm.Session{}).
+		Where("status <> ?", models.OrderStatusCancelled).
+		Select(strings.Join(sumSelects, ", ")).Scan(&sums).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve orders"})
+		return
+	}
+	if !canReadPricing {
+		delete(sums, "price_from_client")
+		delete(sums, "price_for_contractor")
+	}
+	if !canReadSalary {
+		for field := range orderSalaryColumns {
+			delete(sums, field)
+		}
+	}
+
 	var orders []models.Order
-	query := ctrl.DB.Preload("Contractor").
+	if err := base.Session(&gorm.Session{}).
+		Preload("Contractor").
 		Preload("Driver").
 		Preload("Truck").
 		Preload("Client").
-		Where("EXTRACT(MONTH from updated_at) = ? AND EXTRACT(YEAR FROM updated_at) = ?", monthInt, yearInt)
-
-	// add driver id condition if provided
-	if driverId != "all" && driverId != "" {
-		query.Where("driver_id = ?", driverId)
+		Order(order).Limit(page.Limit).Offset(page.Offset).
+		Find(&orders).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve orders"})
+		return
 	}
 
-	// add contractor id condition if provided
-	if contractorId != "all" && contractorId != "" {
-		query.Where("contractor_id = ?", contractorId)
+	for i := range orders {
+		if !canReadPricing {
+			orders[i].HidePricing()
+		}
+		if !canReadSalary {
+			orders[i].HideSalary()
+		}
 	}
 
-	if err := query.Order("updated_at DESC").Find(&orders).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve orders"})
-		return
-	}
-	// Return the orders as a JSON response
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": orders})
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"results": len(orders),
+		"total":   total,
+		"page":    page.Page,
+		"limit":   page.Limit,
+		"sums":    sums,
+		"data":    orders,
+	})
+}
+
+// orderStopsInSequence preloads an order's stops in the order they are visited
+func orderStopsInSequence(db *gorm.DB) *gorm.DB {
+	return db.Order("sequence")
 }
 
 // GetOrder retrieves a specific order by ID
@@ -100,6 +328,9 @@ func (ctrl *OrderController) GetOrderByID(c *gin.Context) {
 		Preload("Driver").
 		Preload("Truck").
 		Preload("Client").
+		Preload("Stops", orderStopsInSequence).
+		Preload("PODs", orderPODsInDeliveryOrder).
+		Preload("PODs.Files").
 		First(&order, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Order not found"})
@@ -109,10 +340,31 @@ func (ctrl *OrderController) GetOrderByID(c *gin.Context) {
 		return
 	}
 
+	if !middleware.HasPermission(c, models.PermPricingRead) {
+		order.HidePricing()
+	}
+	if !middleware.HasPermission(c, models.PermSalaryRead) {
+		order.HideSalary()
+	}
+
+	c.Header("ETag", utils.ETag(order.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "success", "data": order})
 }
 
-// UpdateOrder updates an existing order
+// orderDerivedColumns are the columns an order update derives from the sent
+// fields, written along with them
+var orderDerivedColumns = []string{
+	"order_type", "total_salary", "end_time",
+	"price_from_client", "price_for_contractor", "price_from_client_id", "price_for_contractor_id",
+	"pickup_province", "pickup_district", "delivery_province", "delivery_district",
+	"point_count", "point_salary", "stop_point_salary",
+	"version", "updated_at",
+}
+
+// UpdateOrder updates the sent fields of an existing order. The update is
+// rejected with 409 and the current order when the order changed since the
+// version in the If-Match header, or the version field, was read, and with
+// 428 when neither is sent.
 func (ctrl *OrderController) UpdateOrder(c *gin.Context) {
 	id := c.Param("orderId")
 	var order models.Order
@@ -131,27 +383,474 @@ func (ctrl *OrderController) UpdateOrder(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&order); err != nil {
+	// existing is loaded separately, binding writes through the pointer
+	// fields of order and would change a copy sharing them as well
+	var existing models.Order
+	if err := ctrl.DB.First(&existing, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order"})
+		return
+	}
+
+	// The total is always derived again, a stored one is not a sent one
+	order.TotalSalary = nil
+	if err := c.ShouldBindBodyWith(&order, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
 		return
 	}
+	columns, err := utils.SentColumns(ctrl.DB, &order, c.MustGet(gin.BodyBytesKey).([]byte),
+		"id", "status", "created_at", "deleted_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Send the order fields as a JSON object"})
+		return
+	}
 
-	if err := ctrl.DB.Save(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update order"})
+	expected, err := utils.ExpectedVersion(c.GetHeader("If-Match"), utils.SentVersion(c.MustGet(gin.BodyBytesKey).([]byte)))
+	if errors.Is(err, utils.ErrVersionRequired) {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"status": "fail", "message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
+	if expected != existing.Version {
+		ctrl.orderVersionConflict(c, id)
+		return
+	}
+	order.Version = expected + 1
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "data": order})
+	// The status only changes through UpdateOrderStatus
+	order.ID = existing.ID
+	order.Status = existing.Status
+
+	// Keep the stored prices when the user has no access to pricing data
+	if !middleware.HasPermission(c, models.PermPricingWrite) {
+		order.PriceFromClient = existing.PriceFromClient
+		order.PriceForContractor = existing.PriceForContractor
+		order.PriceFromClientID = existing.PriceFromClientID
+		order.PriceForContractorID = existing.PriceForContractorID
+	}
+	// and the stored driver pay without access to payroll data
+	if !middleware.HasPermission(c, models.PermSalaryWrite) {
+		order.KeepSalary(&existing)
+	}
+
+	// Sent stops replace the stored ones, an empty list removes them
+	if err := order.ApplyStops(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	// Moving the order keeps the length of its window unless a new end is sent
+	if !order.OrderTime.Equal(existing.OrderTime) && order.EndTime != nil && existing.EndTime != nil && order.EndTime.Equal(*existing.EndTime) {
+		end := order.EndTime.Add(order.OrderTime.Sub(existing.OrderTime))
+		order.EndTime = &end
+	}
+	if err := order.ApplyEndTime(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var warnings []string
+	if order.LoadChanged(&existing) {
+		var ok bool
+		if warnings, ok = ctrl.checkTruckLoad(c, &order); !ok {
+			return
+		}
+	}
+
+	var conflicts []models.OrderAssignmentConflict
+	if order.Status != models.OrderStatusCancelled && order.ScheduleChanged(&existing) {
+		var ok bool
+		if conflicts, ok = ctrl.checkAssignment(c, &order); !ok {
+			return
+		}
+	}
+
+	// An invoiced order has been billed, its figures can no longer change
+	if existing.Status == models.OrderStatusInvoiced {
+		if order.Stops != nil {
+			c.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "The order is invoiced, its stops can no longer be changed"})
+			return
+		}
+		if order.TotalSalary == nil {
+			order.TotalSalary = existing.TotalSalary
+		}
+		if order.FinancialChanged(&existing) || order.RouteChanged(&existing) {
+			c.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "The order is invoiced, its prices, fees and route can no longer be changed"})
+			return
+		}
+	} else if !applyTotalSalary(c, &order, &existing) {
+		return
+	}
+
+	// Prices are only looked up again when the route or load changed, so
+	// prices corrected by hand are kept otherwise
+	var pricing *models.OrderPricing
+	if existing.Status != models.OrderStatusInvoiced && order.RouteChanged(&existing) {
+		result, err := ctrl.priceOrder(&order)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to look up the order prices"})
+			return
+		}
+		pricing = &result
+	}
+
+	if err := ctrl.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&order).Where("version = ?", expected).Select(append(columns, orderDerivedColumns...)).Updates(&order)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.ErrVersionConflict
+		}
+		if len(conflicts) > 0 {
+			if err := tx.Create(newAssignmentOverrides(c, order.ID, conflicts)).Error; err != nil {
+				return err
+			}
+		}
+		if order.Stops == nil {
+			return nil
+		}
+		if err := tx.Where("order_id = ?", order.ID).Delete(&models.OrderStop{}).Error; err != nil {
+			return err
+		}
+		if len(order.Stops) == 0 {
+			return nil
+		}
+		return tx.Create(&order.Stops).Error
+	}); err != nil {
+		if errors.Is(err, utils.ErrVersionConflict) {
+			ctrl.orderVersionConflict(c, id)
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update order"})
+		}
+		return
+	}
+
+	if !middleware.HasPermission(c, models.PermPricingRead) {
+		order.HidePricing()
+	}
+	if !middleware.HasPermission(c, models.PermSalaryRead) {
+		order.HideSalary()
+	}
+
+	c.Header("ETag", utils.ETag(order.Version))
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": order, "pricing": pricing, "warnings": warnings})
 }
 
-// DeleteOrder deletes an order by ID
+// orderVersionConflict rejects an update of an order that was changed by
+// someone else since, with the current order to apply the changes to again
+func (ctrl *OrderController) orderVersionConflict(ctx *gin.Context, id string) {
+	var current models.Order
+	if err := ctrl.DB.Preload("Stops", orderStopsInSequence).First(&current, "id = ?", id).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order"})
+		return
+	}
+	if !middleware.HasPermission(ctx, models.PermPricingRead) {
+		current.HidePricing()
+	}
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		current.HideSalary()
+	}
+
+	ctx.Header("ETag", utils.ETag(current.Version))
+	ctx.JSON(http.StatusConflict, gin.H{
+		"status":  "fail",
+		"message": "The order was changed by someone else, apply your changes to the current order and try again",
+		"data":    current,
+	})
+}
+
+// DeleteOrder moves an order to the trash, see TrashController
 func (ctrl *OrderController) DeleteOrder(ctx *gin.Context) {
 	id := ctx.Param("orderId")
 
-	if err := ctrl.DB.Delete(&models.Order{}, "id = ?", id).Error; err != nil {
+	var invoiced int64
+	ctrl.DB.Model(&models.Order{}).Where("id = ? AND status = ?", id, models.OrderStatusInvoiced).Count(&invoiced)
+	if invoiced > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "Invoiced orders cannot be deleted"})
+		return
+	}
+
+	if err := ctrl.DB.WithContext(ctx).Delete(&models.Order{}, "id = ?", id).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to delete order"})
 		return
 	}
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// UpdateOrderStatus moves an order to another status and records who did it
+func (ctrl *OrderController) UpdateOrderStatus(ctx *gin.Context) {
+	id := ctx.Param("orderId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order ID format"})
+		return
+	}
+
+	var payload models.UpdateOrderStatusInput
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if !models.IsValidOrderStatus(payload.Status) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid status"})
+		return
+	}
+
+	var order models.Order
+	if err := ctrl.DB.First(&order, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Order not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order"})
+		}
+		return
+	}
+
+	if !models.CanTransitionOrder(order.Status, payload.Status) {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "An order cannot go from " + order.Status + " to " + payload.Status})
+		return
+	}
+	if payload.Status == models.OrderStatusAssigned && order.DriverID == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Assign a driver to the order first"})
+		return
+	}
+	if payload.Status == models.OrderStatusDelivered {
+		missing, err := orderPODMissing(ctrl.DB, &order)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to check the proof of delivery"})
+			return
+		}
+		if missing {
+			ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "The client requires a proof of delivery, add one before the order is delivered"})
+			return
+		}
+	}
+
+	history := newOrderStatusHistory(ctx, order.ID, order.Status, payload.Status, payload.Note)
+	err := ctrl.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Only move the order if nobody changed its status in the meantime. The
+		// version moves on too, an edit read before the change is stale.
+		result := tx.Model(&order).Where("status = ?", order.Status).
+			Updates(map[string]interface{}{"status": payload.Status, "version": gorm.Expr("version + 1"), "updated_at": history.CreatedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOrderStatusChanged
+		}
+		return tx.Create(history).Error
+	})
+	if err == errOrderStatusChanged {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "The order status was changed by someone else, reload the order"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update order status"})
+		return
+	}
+
+	order.Status = payload.Status
+	order.Version++
+	if !middleware.HasPermission(ctx, models.PermPricingRead) {
+		order.HidePricing()
+	}
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		order.HideSalary()
+	}
+
+	ctx.Header("ETag", utils.ETag(order.Version))
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": order})
+}
+
+// GetOrderHistory lists the status changes of an order, oldest first
+func (ctrl *OrderController) GetOrderHistory(ctx *gin.Context) {
+	id := ctx.Param("orderId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order ID format"})
+		return
+	}
+
+	var history []models.OrderStatusHistory
+	if err := ctrl.DB.Where("order_id = ?", id).Order("created_at").Find(&history).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order history"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(history), "data": history})
+}
+
+var errOrderStatusChanged = errors.New("order status changed")
+
+// newOrderStatusHistory builds a history entry attributed to the current user
+func newOrderStatusHistory(ctx *gin.Context, orderID uuid.UUID, from string, to string, note string) *models.OrderStatusHistory {
+	history := &models.OrderStatusHistory{
+		ID:         uuid.New(),
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		Note:       note,
+		CreatedAt:  time.Now(),
+	}
+	if value, exists := ctx.Get("currentUser"); exists {
+		user := value.(models.User)
+		history.ChangedByID = &user.ID
+		history.ChangedBy = user.Email
+	}
+	return history
+}
+
+// CalculateOrder returns the TotalSalary breakdown of an order without saving it
+func (ctrl *OrderController) CalculateOrder(ctx *gin.Context) {
+	var order models.Order
+	if err := ctx.ShouldBindJSON(&order); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := order.ApplyStops(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := order.CheckExternalPay(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	total, err := order.CalculateTotalSalary()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": total})
+}
+
+// applyTotalSalary derives the order's TotalSalary from its components. A
+// total sent by the client must match it, a mismatch means the UI calculated
+// it differently and the request is rejected with the expected breakdown.
+// previous is the saved order on updates, external orders saved with driver
+// pay stay editable as long as the pay and order type are left alone.
+func applyTotalSalary(ctx *gin.Context, order *models.Order, previous *models.Order) bool {
+	if previous == nil || order.ExternalPayChanged(previous) {
+		if err := order.CheckExternalPay(); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+			return false
+		}
+	}
+
+	total, err := order.CalculateTotalSalary()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return false
+	}
+
+	if order.TotalSalary != nil && math.Round(*order.TotalSalary) != total.TotalSalary {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"status": "fail", "message": "total_salary does not match the order's fees", "data": total})
+		return false
+	}
+
+	order.OrderType = total.OrderType
+	order.TotalSalary = &total.TotalSalary
+	return true
+}
+
+// priceOrder fills the order prices from the latest pricing tables of its
+// client and its contractor. A price without a matching rule is left as it
+// was, the result tells which rule was used or why none applied.
+func (ctrl *OrderController) priceOrder(order *models.Order) (models.OrderPricing, error) {
+	return priceOrderFrom(order, ctrl.latestPricing)
+}
+
+// pricingLoader returns the latest pricing table of an owner with its price
+// details, nil when the owner has none
+type pricingLoader func(ownerType string, ownerID uuid.UUID) (*models.Pricing, error)
+
+func (ctrl *OrderController) latestPricing(ownerType string, ownerID uuid.UUID) (*models.Pricing, error) {
+	var pricing models.Pricing
+	err := ctrl.DB.Preload("PriceDetails").
+		Where("owner_id = ? AND owner_type = ?", ownerID, ownerType).
+		Order("created_at DESC").
+		First(&pricing).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &pricing, nil
+}
+
+// priceOrderFrom is priceOrder with the pricing tables taken from latest
+func priceOrderFrom(order *models.Order, latest pricingLoader) (models.OrderPricing, error) {
+	var pricing models.OrderPricing
+	var err error
+
+	var clientPrice float64
+	pricing.Client, clientPrice, err = lookupPrice(latest, models.PricingOwnerClient, order.ClientID, order)
+	if err != nil {
+		return pricing, err
+	}
+	if pricing.Client.Matched {
+		order.PriceFromClient = &clientPrice
+		order.PriceFromClientID = pricing.Client.PriceDetailID
+	}
+
+	var contractorPrice float64
+	pricing.Contractor, contractorPrice, err = lookupPrice(latest, models.PricingOwnerContractor, order.ContractorID, order)
+	if err != nil {
+		return pricing, err
+	}
+	if pricing.Contractor.Matched {
+		order.PriceForContractor = &contractorPrice
+		order.PriceForContractorID = pricing.Contractor.PriceDetailID
+	}
+
+	return pricing, nil
+}
+
+// lookupPrice finds the most specific price detail of the owner's latest
+// pricing table that covers the order's route and has a tier for its load
+func lookupPrice(latest pricingLoader, ownerType string, ownerID uuid.UUID, order *models.Order) (models.PriceMatch, float64, error) {
+	if ownerID == uuid.Nil {
+		return models.PriceMatch{Reason: "The order has no " + ownerType}, 0, nil
+	}
+
+	pricing, err := latest(ownerType, ownerID)
+	if err != nil {
+		return models.PriceMatch{}, 0, err
+	}
+	if pricing == nil {
+		return models.PriceMatch{Reason: "The " + ownerType + " has no pricing table"}, 0, nil
+	}
+
+	match := models.PriceMatch{PricingID: &pricing.ID, Reason: "No price detail covers the route"}
+	var price float64
+	bestSpecificity := -1
+	weight, _ := order.WeightInTons()
+	for i := range pricing.PriceDetails {
+		detail := &pricing.PriceDetails[i]
+		specificity := detail.RouteSpecificity(order.PickupProvince, order.PickupDistrict, order.DeliveryProvince, order.DeliveryDistrict)
+		if specificity < 0 {
+			continue
+		}
+
+		tier, tierPrice, ok := detail.WeightPrices.Tier(weight, order.PackageVolume)
+		if !ok {
+			if !match.Matched {
+				match.Reason = "No weight or volume tier fits the load"
+				if order.PackageWeight != nil && *order.PackageWeight > 0 && !order.WeightUnitKnown() {
+					match.Reason = "The weight unit is not kg or t, weight tiers could not be used"
+				}
+			}
+			continue
+		}
+
+		if specificity > bestSpecificity {
+			bestSpecificity = specificity
+			match = models.PriceMatch{Matched: true, PricingID: &pricing.ID, PriceDetailID: &detail.ID, Tier: tier}
+			price = tierPrice
+		}
+	}
+
+	return match, price, nil
+}
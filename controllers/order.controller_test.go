@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"gorm.io/gorm/schema"
+)
+
+func TestOrderColumnsMatchTheSchema(t *testing.T) {
+	orderSchema, err := schema.Parse(&models.Order{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := make(map[string]string)
+	for _, field := range orderSchema.Fields {
+		if field.DBName != "" {
+			columns[field.DBName] = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+	}
+
+	for name, fields := range map[string]map[string]string{
+		"orderSumColumns":       orderSumColumns,
+		"orderSalaryColumns":    orderSalaryColumns,
+		"orderSortFields":       orderSortFields,
+		"orderSalarySortFields": orderSalarySortFields,
+		"orderPriceSortFields":  orderPriceSortFields,
+	} {
+		for field, column := range fields {
+			jsonName, ok := columns[column]
+			if !ok {
+				t.Errorf("%s: %s maps to %s, which is not a column of orders", name, field, column)
+			} else if jsonName != field {
+				t.Errorf("%s: column %s is %s in JSON, not %s", name, column, jsonName, field)
+			}
+		}
+	}
+
+	for field, column := range orderSalaryColumns {
+		if orderSumColumns[field] != column {
+			t.Errorf("salary field %s is not added up by GetOrders", field)
+		}
+	}
+}
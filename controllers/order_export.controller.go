@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+// maxExportRows keeps exports to a size the server can build in memory
+const maxExportRows = 20000
+
+// orderExportColumns are exported after order_type, on top of the template
+// columns. Imports ignore them.
+var orderExportColumns = []orderSheetColumn{
+	{"status", "Trạng thái"},
+}
+
+// orderExportFormats are the spreadsheet formats of the export columns,
+// columns not listed are text
+var orderExportFormats = map[string]string{
+	"order_time":           utils.SheetFormatDate,
+	"package_weight":       utils.SheetFormatNumber,
+	"package_volume":       utils.SheetFormatNumber,
+	"trip_count":           utils.SheetFormatNumber,
+	"point_count":          utils.SheetFormatNumber,
+	"trip_salary":          utils.SheetFormatVND,
+	"point_salary":         utils.SheetFormatVND,
+	"daily_salary":         utils.SheetFormatVND,
+	"loading_salary":       utils.SheetFormatVND,
+	"meal_fee":             utils.SheetFormatVND,
+	"standby_fee":          utils.SheetFormatVND,
+	"parking_fee":          utils.SheetFormatVND,
+	"charge_fee":           utils.SheetFormatVND,
+	"recovery_fee":         utils.SheetFormatVND,
+	"oil_fee":              utils.SheetFormatVND,
+	"outside_oil_fee":      utils.SheetFormatVND,
+	"other_salary":         utils.SheetFormatVND,
+	"total_salary":         utils.SheetFormatVND,
+	"price_from_client":    utils.SheetFormatVND,
+	"price_for_contractor": utils.SheetFormatVND,
+}
+
+// orderExportWidths are the widths of the wider export columns
+var orderExportWidths = map[string]float64{
+	"order_time": 17,
+	"contractor": 24,
+	"client":     24,
+	"driver":     24,
+	"notes":      40,
+}
+
+// orderExportGroups are the group_by values of ExportOrders with the label of
+// an order's group
+var orderExportGroups = map[string]func(order *models.Order) string{
+	"driver": func(order *models.Order) string {
+		if order.DriverID == nil {
+			return "Chưa có tài xế"
+		}
+		return order.Driver.FullName
+	},
+	"contractor": func(order *models.Order) string {
+		return order.Contractor.Name
+	},
+}
+
+// ExportOrders downloads the orders matching the GetOrders filters as an
+// XLSX workbook, or as CSV with format=csv. Columns follow the import
+// template with Vietnamese titles. group_by=driver or group_by=contractor
+// groups the orders and adds a subtotal row after each group. The last row
+// holds the totals. Cancelled orders are not counted in the subtotals and
+// totals. Price columns are left out without access to pricing
+// data, driver pay columns without access to payroll data.
+func (ctrl *OrderController) ExportOrders(ctx *gin.Context) {
+	canReadPricing := middleware.HasPermission(ctx, models.PermPricingRead)
+	canReadSalary := middleware.HasPermission(ctx, models.PermSalaryRead)
+
+	format := ctx.DefaultQuery("format", "xlsx")
+	if format != "xlsx" && format != "csv" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "format must be xlsx or csv"})
+		return
+	}
+
+	groupBy := ctx.Query("group_by")
+	groupLabel, grouped := orderExportGroups[groupBy]
+	if groupBy != "" && !grouped {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "group_by must be driver or contractor"})
+		return
+	}
+
+	order, ok := orderListSort(ctx, canReadPricing, canReadSalary)
+	if !ok {
+		return
+	}
+
+	base, ok := ctrl.orderListQuery(ctx)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to export orders"})
+		return
+	}
+	if total > maxExportRows {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": fmt.Sprintf("%d orders match, narrow the filters to at most %d", total, maxExportRows)})
+		return
+	}
+
+	var orders []models.Order
+	if err := base.Session(&gorm.Session{}).
+		Preload("Contractor").
+		Preload("Driver").
+		Preload("Truck").
+		Preload("Client").
+		Order(order).
+		Find(&orders).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to export orders"})
+		return
+	}
+
+	// Groups are sorted by label, orders keep the requested order inside them
+	if grouped {
+		sort.SliceStable(orders, func(i, j int) bool {
+			return groupLabel(&orders[i]) < groupLabel(&orders[j])
+		})
+	}
+
+	var keys []string
+	var columns []utils.SheetColumn
+	for _, column := range orderExportSheetColumns() {
+		if !canReadPricing && (column.Key == "price_from_client" || column.Key == "price_for_contractor") {
+			continue
+		}
+		if !canReadSalary && isOrderSalaryColumn(column.Key) {
+			continue
+		}
+		keys = append(keys, column.Key)
+		columns = append(columns, utils.SheetColumn{
+			Title:  column.Header,
+			Format: orderExportFormats[column.Key],
+			Width:  orderExportWidths[column.Key],
+		})
+	}
+
+	var rows []utils.SheetRow
+	totals := newOrderExportTotals(keys)
+	subtotals := newOrderExportTotals(keys)
+	for i := range orders {
+		values := orderExportValues(&orders[i])
+		row := utils.SheetRow{Cells: make([]interface{}, len(keys))}
+		for k, key := range keys {
+			row.Cells[k] = values[key]
+		}
+		rows = append(rows, row)
+		// Cancelled orders are listed but not added up
+		if orders[i].Status != models.OrderStatusCancelled {
+			totals.add(values)
+			subtotals.add(values)
+		}
+
+		if grouped && (i == len(orders)-1 || groupLabel(&orders[i]) != groupLabel(&orders[i+1])) {
+			rows = append(rows, subtotals.row("Cộng "+groupLabel(&orders[i])))
+			subtotals = newOrderExportTotals(keys)
+		}
+	}
+	rows = append(rows, totals.row("Tổng cộng"))
+
+	filename := "orders-" + time.Now().Format("20060102-150405")
+	var file bytes.Buffer
+	if format == "csv" {
+		if err := utils.WriteCSV(&file, columns, rows); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to export orders"})
+			return
+		}
+		ctx.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		ctx.Data(http.StatusOK, "text/csv; charset=utf-8", file.Bytes())
+		return
+	}
+
+	if err := utils.WriteXLSX(&file, "Đơn hàng", columns, rows); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to export orders"})
+		return
+	}
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`.xlsx"`)
+	ctx.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", file.Bytes())
+}
+
+// isOrderSalaryColumn reports whether the field, by its JSON name, holds
+// driver pay
+func isOrderSalaryColumn(key string) bool {
+	_, ok := orderSalaryColumns[key]
+	return ok
+}
+
+// isOrderSalaryDBColumn reports whether the database column holds driver pay
+func isOrderSalaryDBColumn(column string) bool {
+	for _, salaryColumn := range orderSalaryColumns {
+		if salaryColumn == column {
+			return true
+		}
+	}
+	return false
+}
+
+// orderExportSheetColumns returns the template columns with the export only
+// columns after order_type
+func orderExportSheetColumns() []orderSheetColumn {
+	columns := make([]orderSheetColumn, 0, len(orderSheetColumns)+len(orderExportColumns))
+	for _, column := range orderSheetColumns {
+		columns = append(columns, column)
+		if column.Key == "order_type" {
+			columns = append(columns, orderExportColumns...)
+		}
+	}
+	return columns
+}
+
+// orderExportValues returns the cells of an order by column key, using the
+// names of the preloaded contractor, client, driver and truck
+func orderExportValues(order *models.Order) map[string]interface{} {
+	values := map[string]interface{}{
+		"order_time":           order.OrderTime,
+		"order_type":           order.OrderType,
+		"status":               order.Status,
+		"contractor":           order.Contractor.Name,
+		"client":               order.Client.Name,
+		"driver":               order.Driver.FullName,
+		"truck":                order.Truck.LicensePlate,
+		"pickup_province":      order.PickupProvince,
+		"pickup_district":      order.PickupDistrict,
+		"delivery_province":    order.DeliveryProvince,
+		"delivery_district":    order.DeliveryDistrict,
+		"unit":                 order.Unit,
+		"trip_count":           order.TripCount,
+		"notes":                order.Notes,
+		"package_weight":       order.PackageWeight,
+		"weight_unit":          order.WeightUnit,
+		"package_volume":       order.PackageVolume,
+		"trip_salary":          order.TripSalary,
+		"point_salary":         order.PointSalary,
+		"daily_salary":         order.DailySalary,
+		"loading_salary":       order.LoadingSalary,
+		"meal_fee":             order.MealFee,
+		"standby_fee":          order.StandbyFee,
+		"parking_fee":          order.ParkingFee,
+		"charge_fee":           order.ChargeFee,
+		"recovery_fee":         order.RefundFee,
+		"oil_fee":              order.OilFee,
+		"outside_oil_fee":      order.OutsiteOilFee,
+		"other_salary":         order.OtherSalary,
+		"total_salary":         order.TotalSalary,
+		"price_from_client":    order.PriceFromClient,
+		"price_for_contractor": order.PriceForContractor,
+	}
+	if order.PointCount != nil {
+		values["point_count"] = *order.PointCount
+	}
+
+	// Pointers are written as their value, missing values as empty cells
+	for key, value := range values {
+		if number, ok := value.(*float64); ok {
+			if number == nil {
+				values[key] = nil
+			} else {
+				values[key] = *number
+			}
+		}
+	}
+	return values
+}
+
+// orderExportTotals adds up the number and money columns of exported orders
+type orderExportTotals struct {
+	keys []string
+	sums map[string]float64
+}
+
+func newOrderExportTotals(keys []string) *orderExportTotals {
+	return &orderExportTotals{keys: keys, sums: make(map[string]float64)}
+}
+
+func (t *orderExportTotals) add(values map[string]interface{}) {
+	for _, key := range t.keys {
+		if orderExportFormats[key] != utils.SheetFormatVND && key != "trip_count" {
+			continue
+		}
+		switch value := values[key].(type) {
+		case float64:
+			t.sums[key] += value
+		case int:
+			t.sums[key] += float64(value)
+		}
+	}
+}
+
+// row returns a bold row with the label in the first column and the sums
+func (t *orderExportTotals) row(label string) utils.SheetRow {
+	row := utils.SheetRow{Cells: make([]interface{}, len(t.keys)), Bold: true}
+	row.Cells[0] = label
+	for i, key := range t.keys {
+		if sum, ok := t.sums[key]; ok {
+			row.Cells[i] = sum
+		}
+	}
+	return row
+}
@@ -0,0 +1,716 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+// orderSheetColumn is a column of the order spreadsheet template. Imports
+// accept either the key or the Vietnamese header as the column title.
+type orderSheetColumn struct {
+	Key    string
+	Header string
+}
+
+// orderSheetColumns is the order spreadsheet template. contractor, client and
+// driver are names or phone numbers, truck is a license plate.
+var orderSheetColumns = []orderSheetColumn{
+	{"order_time", "Ngày"},
+	{"order_type", "Loại đơn"},
+	{"contractor", "Nhà thầu"},
+	{"client", "Khách hàng"},
+	{"driver", "Tài xế"},
+	{"truck", "Biển số xe"},
+	{"pickup_province", "Tỉnh lấy hàng"},
+	{"pickup_district", "Huyện lấy hàng"},
+	{"delivery_province", "Tỉnh giao hàng"},
+	{"delivery_district", "Huyện giao hàng"},
+	{"unit", "Đơn vị tính"},
+	{"package_weight", "Trọng lượng"},
+	{"weight_unit", "Đơn vị trọng lượng"},
+	{"package_volume", "Thể tích"},
+	{"trip_count", "Số chuyến"},
+	{"point_count", "Số điểm"},
+	{"trip_salary", "Lương chuyến"},
+	{"point_salary", "Lương điểm"},
+	{"daily_salary", "Lương ngày"},
+	{"loading_salary", "Bốc xếp"},
+	{"meal_fee", "Tiền ăn"},
+	{"standby_fee", "Phí chờ"},
+	{"parking_fee", "Phí gửi xe"},
+	{"charge_fee", "Phí cầu đường"},
+	{"recovery_fee", "Phí thu hồi"},
+	{"oil_fee", "Tiền dầu"},
+	{"outside_oil_fee", "Dầu ngoài"},
+	{"other_salary", "Chi phí khác"},
+	{"total_salary", "Tổng lương"},
+	{"price_from_client", "Giá khách hàng"},
+	{"price_for_contractor", "Giá nhà thầu"},
+	{"notes", "Ghi chú"},
+}
+
+// requiredImportColumns must be present in an imported sheet
+var requiredImportColumns = []string{"order_time", "contractor", "pickup_province", "delivery_province", "unit"}
+
+const (
+	maxImportFileSize = 10 << 20
+	maxImportRows     = 5000
+)
+
+// OrderImportRow is the validation result of one row of an imported sheet
+type OrderImportRow struct {
+	Row      int                  `json:"row"`
+	Valid    bool                 `json:"valid"`
+	Errors   []string             `json:"errors,omitempty"`
+	Warnings []string             `json:"warnings,omitempty"`
+	Pricing  *models.OrderPricing `json:"pricing,omitempty"`
+	Order    *models.Order        `json:"order,omitempty"`
+}
+
+// GetImportTemplate returns an empty CSV file with the columns ImportOrders reads
+func (ctrl *OrderController) GetImportTemplate(ctx *gin.Context) {
+	headers := make([]string, 0, len(orderSheetColumns))
+	for _, column := range orderSheetColumns {
+		headers = append(headers, column.Key)
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="orders-template.csv"`)
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", []byte(strings.Join(headers, ",")+"\n"))
+}
+
+// ImportOrders reads orders from an uploaded XLSX or CSV file, the "file"
+// form field, laid out as the template. The first row holds the column
+// titles. Every row is validated and the report lists the errors per row.
+// Nothing is saved unless the commit query is true, then every valid row is
+// saved in one transaction and invalid rows are skipped. Rows that
+// double-book a driver or truck are invalid, unless the force query is true
+// and the caller may override the check as in CreateOrder.
+func (ctrl *OrderController) ImportOrders(ctx *gin.Context) {
+	commit := ctx.Query("commit") == "true"
+	force := ctx.Query("force") == "true"
+	if force && !middleware.HasPermission(ctx, models.PermOrdersOverride) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "You are not allowed to double-book a driver or truck"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportFileSize)
+	file, fileHeader, err := ctx.Request.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Upload a .xlsx or .csv file of at most 10 MB in the file field"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Could not read the uploaded file"})
+		return
+	}
+
+	rows, err := utils.ReadSpreadsheet(fileHeader.Filename, data)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	// The first row with content holds the column titles
+	headerRow := 0
+	for headerRow < len(rows) && isBlankRow(rows[headerRow]) {
+		headerRow++
+	}
+	if headerRow == len(rows) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "The file is empty"})
+		return
+	}
+	if len(rows)-headerRow-1 > maxImportRows {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": fmt.Sprintf("A file can hold at most %d orders", maxImportRows)})
+		return
+	}
+
+	columns, ignored := mapImportColumns(rows[headerRow])
+	var missing []string
+	for _, key := range requiredImportColumns {
+		if _, ok := columns[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Missing columns: " + strings.Join(missing, ", ")})
+		return
+	}
+
+	resolver, err := newOrderImportResolver(ctrl.DB.WithContext(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to load contractors, clients, drivers and trucks"})
+		return
+	}
+
+	canWritePricing := middleware.HasPermission(ctx, models.PermPricingWrite)
+	canWriteSalary := middleware.HasPermission(ctx, models.PermSalaryWrite)
+	var report []OrderImportRow
+	var orders []models.Order
+	var orderRows []int
+	for i := headerRow + 1; i < len(rows); i++ {
+		if isBlankRow(rows[i]) {
+			continue
+		}
+
+		result, order := ctrl.importOrderRow(resolver, columns, rows[i], canWritePricing, canWriteSalary)
+		result.Row = i + 1
+		if result.Valid {
+			orders = append(orders, order)
+			orderRows = append(orderRows, len(report))
+		}
+		report = append(report, result)
+	}
+
+	// The ids are given before the schedule check so that rows booking the
+	// same driver or truck can refer to each other
+	for i := range orders {
+		orders[i].ID = uuid.New()
+		orders[i].Status = models.OrderStatusDraft
+	}
+	orders, orderRows, conflicts, err := checkImportConflicts(ctrl.DB.WithContext(ctx), report, orders, orderRows, force)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to check the driver and truck schedule"})
+		return
+	}
+
+	imported := 0
+	if commit && len(orders) > 0 {
+		err := ctrl.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			history := make([]*models.OrderStatusHistory, 0, len(orders))
+			var overrides []models.OrderAssignmentOverride
+			for i := range orders {
+				history = append(history, newOrderStatusHistory(ctx, orders[i].ID, "", orders[i].Status, "Imported from "+fileHeader.Filename))
+				if len(conflicts[i]) > 0 {
+					overrides = append(overrides, newAssignmentOverrides(ctx, orders[i].ID, conflicts[i])...)
+				}
+			}
+			if err := tx.CreateInBatches(&orders, 200).Error; err != nil {
+				return err
+			}
+			if len(overrides) > 0 {
+				if err := tx.CreateInBatches(overrides, 200).Error; err != nil {
+					return err
+				}
+			}
+			return tx.CreateInBatches(history, 200).Error
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to import orders, nothing was saved"})
+			return
+		}
+		imported = len(orders)
+
+		// Show the saved orders, with their ids, in the report
+		for i, row := range orderRows {
+			report[row].Order = &orders[i]
+		}
+	}
+
+	canReadPricing := middleware.HasPermission(ctx, models.PermPricingRead)
+	canReadSalary := middleware.HasPermission(ctx, models.PermSalaryRead)
+	for i := range report {
+		if report[i].Order == nil {
+			continue
+		}
+		if !canReadPricing {
+			report[i].Order.HidePricing()
+		}
+		if !canReadSalary {
+			report[i].Order.HideSalary()
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":          "success",
+		"dry_run":         !commit,
+		"rows":            len(report),
+		"valid_rows":      len(orders),
+		"invalid_rows":    len(report) - len(orders),
+		"imported":        imported,
+		"ignored_columns": ignored,
+		"data":            report,
+	})
+}
+
+// importOrderRow turns a row into an order and validates it
+func (ctrl *OrderController) importOrderRow(resolver *orderImportResolver, columns map[string]int, row []string, canWritePricing bool, canWriteSalary bool) (OrderImportRow, models.Order) {
+	var result OrderImportRow
+	cell := func(key string) string {
+		index, ok := columns[key]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+	fail := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	order := models.Order{
+		OrderType:        strings.ToLower(cell("order_type")),
+		PickupProvince:   cell("pickup_province"),
+		PickupDistrict:   cell("pickup_district"),
+		DeliveryProvince: cell("delivery_province"),
+		DeliveryDistrict: cell("delivery_district"),
+		Unit:             cell("unit"),
+		WeightUnit:       strings.ToLower(cell("weight_unit")),
+		Notes:            cell("notes"),
+		TripCount:        1,
+	}
+
+	for _, key := range requiredImportColumns {
+		if cell(key) == "" {
+			fail("%s is required", key)
+		}
+	}
+
+	if value := cell("order_time"); value != "" {
+		orderTime, err := utils.ParseSpreadsheetTime(value)
+		if err != nil {
+			fail("order_time: %s", err)
+		}
+		order.OrderTime = orderTime
+	}
+
+	if value := cell("contractor"); value != "" {
+		if id, err := resolver.contractors.resolve("contractor", value); err != nil {
+			fail("%s", err)
+		} else {
+			order.ContractorID = id
+		}
+	}
+	if value := cell("client"); value != "" {
+		if id, err := resolver.clients.resolve("client", value); err != nil {
+			fail("%s", err)
+		} else {
+			order.ClientID = id
+		}
+	}
+	if value := cell("driver"); value != "" {
+		if id, err := resolver.drivers.resolve("driver", value); err != nil {
+			fail("%s", err)
+		} else if order.ContractorID != uuid.Nil && resolver.driverContractors[id] != order.ContractorID {
+			fail("driver %q does not work for the contractor", value)
+		} else {
+			order.DriverID = &id
+		}
+	}
+	if value := cell("truck"); value != "" {
+		if id, err := resolver.trucks.resolve("truck", value); err != nil {
+			fail("%s", err)
+		} else if order.ContractorID != uuid.Nil && resolver.truckContractors[id] != order.ContractorID {
+			fail("truck %q does not belong to the contractor", value)
+		} else {
+			order.TruckID = &id
+		}
+	}
+
+	numbers := []struct {
+		key    string
+		target **float64
+		amount bool
+	}{
+		{"package_weight", &order.PackageWeight, false},
+		{"package_volume", &order.PackageVolume, false},
+		{"trip_salary", &order.TripSalary, true},
+		{"point_salary", &order.PointSalary, true},
+		{"daily_salary", &order.DailySalary, true},
+		{"loading_salary", &order.LoadingSalary, true},
+		{"meal_fee", &order.MealFee, true},
+		{"standby_fee", &order.StandbyFee, true},
+		{"parking_fee", &order.ParkingFee, true},
+		{"charge_fee", &order.ChargeFee, true},
+		{"recovery_fee", &order.RefundFee, true},
+		{"oil_fee", &order.OilFee, true},
+		{"outside_oil_fee", &order.OutsiteOilFee, true},
+		{"other_salary", &order.OtherSalary, true},
+		{"total_salary", &order.TotalSalary, true},
+		{"price_from_client", &order.PriceFromClient, true},
+		{"price_for_contractor", &order.PriceForContractor, true},
+	}
+	for _, number := range numbers {
+		value := cell(number.key)
+		if value == "" {
+			continue
+		}
+		parse := utils.ParseSpreadsheetNumber
+		if number.amount {
+			parse = utils.ParseSpreadsheetAmount
+		}
+		parsed, err := parse(value)
+		if err != nil {
+			fail("%s: %s", number.key, err)
+			continue
+		}
+		*number.target = &parsed
+	}
+
+	for _, count := range []struct {
+		key    string
+		target func(int)
+	}{
+		{"trip_count", func(value int) { order.TripCount = value }},
+		{"point_count", func(value int) { order.PointCount = &value }},
+	} {
+		value := cell(count.key)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			fail("%s: invalid count %q", count.key, value)
+			continue
+		}
+		count.target(parsed)
+	}
+
+	// Overloaded trucks are errors as in CreateOrder. Imported orders keep
+	// their driver and truck busy for the default duration, double bookings
+	// are checked for the whole sheet by checkImportConflicts.
+	order.ApplyEndTime()
+	if len(result.Errors) == 0 && order.TruckID != nil {
+		check := order.CheckTruckLoad(resolver.truckRecords[*order.TruckID])
+		for _, message := range check.Errors {
+			fail("%s", message)
+		}
+		result.Warnings = append(result.Warnings, check.Warnings...)
+	}
+
+	// Driver pay from the sheet is only taken from users who may set it
+	if !canWriteSalary {
+		order.HideSalary()
+	}
+	if err := order.CheckExternalPay(); err != nil {
+		fail("%s", err)
+	}
+	total, err := order.CalculateTotalSalary()
+	if err != nil {
+		fail("%s", err)
+	} else if order.TotalSalary != nil && math.Round(*order.TotalSalary) != total.TotalSalary {
+		fail("total_salary is %.0f but the fees add up to %.0f", *order.TotalSalary, total.TotalSalary)
+	} else {
+		order.OrderType = total.OrderType
+		order.TotalSalary = &total.TotalSalary
+	}
+
+	// Prices from the sheet win when the user may set prices, the others are
+	// looked up in the pricing tables
+	if !canWritePricing {
+		order.HidePricing()
+	}
+	givenClientPrice, givenContractorPrice := order.PriceFromClient, order.PriceForContractor
+	if len(result.Errors) == 0 {
+		pricing, err := priceOrderFrom(&order, resolver.latestPricing)
+		if err != nil {
+			fail("could not look up the prices")
+		} else {
+			result.Pricing = &pricing
+			if givenClientPrice != nil {
+				order.PriceFromClient, order.PriceFromClientID = givenClientPrice, nil
+			} else if !pricing.Client.Matched {
+				result.Warnings = append(result.Warnings, "client price: "+pricing.Client.Reason)
+			}
+			if givenContractorPrice != nil {
+				order.PriceForContractor, order.PriceForContractorID = givenContractorPrice, nil
+			} else if !pricing.Contractor.Matched {
+				result.Warnings = append(result.Warnings, "contractor price: "+pricing.Contractor.Reason)
+			}
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	result.Order = &order
+	return result, order
+}
+
+// mapImportColumns finds the template columns in the title row. It returns
+// the titles it did not recognise.
+func mapImportColumns(titles []string) (map[string]int, []string) {
+	known := make(map[string]string)
+	for _, column := range orderSheetColumns {
+		known[normalizeColumnTitle(column.Key)] = column.Key
+		known[normalizeColumnTitle(column.Header)] = column.Key
+	}
+
+	columns := make(map[string]int)
+	ignored := []string{}
+	for i, title := range titles {
+		if strings.TrimSpace(title) == "" {
+			continue
+		}
+		key, ok := known[normalizeColumnTitle(title)]
+		if !ok {
+			ignored = append(ignored, title)
+			continue
+		}
+		if _, seen := columns[key]; !seen {
+			columns[key] = i
+		}
+	}
+	return columns, ignored
+}
+
+func normalizeColumnTitle(title string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(title, "_", " "))), " ")
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// importLookup finds records by any of their names, phone numbers or plates
+type importLookup map[string][]uuid.UUID
+
+func (l importLookup) add(id uuid.UUID, keys ...string) {
+	for _, key := range keys {
+		key = normalizeLookupKey(key)
+		if key == "" || (len(l[key]) > 0 && l[key][len(l[key])-1] == id) {
+			continue
+		}
+		l[key] = append(l[key], id)
+	}
+}
+
+func (l importLookup) resolve(kind string, value string) (uuid.UUID, error) {
+	ids := l[normalizeLookupKey(value)]
+	switch {
+	case len(ids) == 0:
+		return uuid.Nil, fmt.Errorf("no %s matches %q", kind, value)
+	case len(ids) > 1:
+		return uuid.Nil, fmt.Errorf("%q matches %d %ss, use the phone number", value, len(ids), kind)
+	}
+	return ids[0], nil
+}
+
+// normalizeLookupKey ignores case, spaces and the punctuation people put in
+// phone numbers and license plates, "51C-123.45" matches "51c12345"
+func normalizeLookupKey(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' || r == '.' || r == '(' || r == ')' || r == '+' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, value)
+}
+
+// orderImportResolver holds the records the rows of a sheet refer to. The
+// pricing tables are loaded the first time a row needs them.
+type orderImportResolver struct {
+	db                *gorm.DB
+	contractors       importLookup
+	clients           importLookup
+	drivers           importLookup
+	trucks            importLookup
+	driverContractors map[uuid.UUID]uuid.UUID
+	truckContractors  map[uuid.UUID]uuid.UUID
+	truckRecords      map[uuid.UUID]*models.Truck
+	pricings          map[string]*models.Pricing
+}
+
+func newOrderImportResolver(db *gorm.DB) (*orderImportResolver, error) {
+	resolver := &orderImportResolver{
+		db:                db,
+		contractors:       importLookup{},
+		clients:           importLookup{},
+		drivers:           importLookup{},
+		trucks:            importLookup{},
+		driverContractors: make(map[uuid.UUID]uuid.UUID),
+		truckContractors:  make(map[uuid.UUID]uuid.UUID),
+		truckRecords:      make(map[uuid.UUID]*models.Truck),
+		pricings:          make(map[string]*models.Pricing),
+	}
+
+	var contractors []models.Contractor
+	if err := db.Find(&contractors).Error; err != nil {
+		return nil, err
+	}
+	for _, contractor := range contractors {
+		resolver.contractors.add(contractor.ID, contractor.Name, contractor.Phone)
+	}
+
+	var clients []models.Client
+	if err := db.Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		resolver.clients.add(client.ID, client.Name, client.Phone)
+	}
+
+	var drivers []models.Driver
+	if err := db.Find(&drivers).Error; err != nil {
+		return nil, err
+	}
+	for _, driver := range drivers {
+		resolver.drivers.add(driver.ID, driver.FullName, driver.Phone)
+		resolver.driverContractors[driver.ID] = driver.ContractorID
+	}
+
+	var trucks []models.Truck
+	if err := db.Find(&trucks).Error; err != nil {
+		return nil, err
+	}
+	for i := range trucks {
+		truck := &trucks[i]
+		resolver.trucks.add(truck.ID, truck.LicensePlate)
+		resolver.truckContractors[truck.ID] = truck.ContractorID
+		resolver.truckRecords[truck.ID] = truck
+	}
+
+	return resolver, nil
+}
+
+// latestPricing is a pricingLoader that loads each owner's table once
+func (r *orderImportResolver) latestPricing(ownerType string, ownerID uuid.UUID) (*models.Pricing, error) {
+	key := ownerType + ":" + ownerID.String()
+	if pricing, ok := r.pricings[key]; ok {
+		return pricing, nil
+	}
+
+	var pricings []models.Pricing
+	if err := r.db.Preload("PriceDetails").
+		Where("owner_id = ? AND owner_type = ?", ownerID, ownerType).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&pricings).Error; err != nil {
+		return nil, err
+	}
+	var pricing *models.Pricing
+	if len(pricings) > 0 {
+		pricing = &pricings[0]
+	}
+	r.pricings[key] = pricing
+	return pricing, nil
+}
+
+// checkImportConflicts checks the valid rows whose driver or truck is
+// already booked, by a saved order or by an earlier valid row of the sheet.
+// The saved orders are loaded in one query for the whole sheet. Those rows
+// are invalid unless override is true, then they are kept with a warning.
+// It returns the orders and report indexes that are still valid and, in the
+// same order, the conflicts to record with newAssignmentOverrides.
+func checkImportConflicts(db *gorm.DB, report []OrderImportRow, orders []models.Order, orderRows []int, override bool) ([]models.Order, []int, [][]models.OrderAssignmentConflict, error) {
+	var start, end time.Time
+	var driverIDs, truckIDs []uuid.UUID
+	for i := range orders {
+		order := &orders[i]
+		if order.EndTime == nil || (order.DriverID == nil && order.TruckID == nil) {
+			continue
+		}
+		if start.IsZero() || order.OrderTime.Before(start) {
+			start = order.OrderTime
+		}
+		if order.EndTime.After(end) {
+			end = *order.EndTime
+		}
+		if order.DriverID != nil {
+			driverIDs = append(driverIDs, *order.DriverID)
+		}
+		if order.TruckID != nil {
+			truckIDs = append(truckIDs, *order.TruckID)
+		}
+	}
+	conflicts := make([][]models.OrderAssignmentConflict, len(orders))
+	if len(driverIDs) == 0 && len(truckIDs) == 0 {
+		return orders, orderRows, conflicts, nil
+	}
+
+	var saved []models.Order
+	if err := busyOrders(db, start, end).
+		Select("id", "status", "order_time", "end_time", "driver_id", "truck_id").
+		Where(db.Where("driver_id IN ?", driverIDs).Or("truck_id IN ?", truckIDs)).
+		Order("order_time").
+		Find(&saved).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	// booked lists the saved orders and the valid rows checked so far per
+	// driver and truck
+	type bookingKey struct {
+		resource string
+		id       uuid.UUID
+	}
+	type booking struct {
+		order *models.Order
+		by    string
+	}
+	booked := make(map[bookingKey][]booking)
+	bookingKeys := func(order *models.Order) []bookingKey {
+		var keys []bookingKey
+		if order.DriverID != nil {
+			keys = append(keys, bookingKey{models.ResourceDriver, *order.DriverID})
+		}
+		if order.TruckID != nil {
+			keys = append(keys, bookingKey{models.ResourceTruck, *order.TruckID})
+		}
+		return keys
+	}
+	for i := range saved {
+		for _, key := range bookingKeys(&saved[i]) {
+			booked[key] = append(booked[key], booking{&saved[i], "order " + saved[i].ID.String()})
+		}
+	}
+
+	validOrders := make([]models.Order, 0, len(orders))
+	validRows := make([]int, 0, len(orderRows))
+	validConflicts := make([][]models.OrderAssignmentConflict, 0, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		row := &report[orderRows[i]]
+		var messages []string
+		if order.EndTime != nil {
+			for _, key := range bookingKeys(order) {
+				for _, other := range booked[key] {
+					if other.order.EndTime == nil || !other.order.OrderTime.Before(*order.EndTime) || !other.order.EndTime.After(order.OrderTime) {
+						continue
+					}
+					messages = append(messages, fmt.Sprintf("%s is already booked by %s at %s", key.resource, other.by, other.order.OrderTime.Format("02/01/2006 15:04")))
+					conflicts[i] = append(conflicts[i], models.OrderAssignmentConflict{
+						Resource:   key.resource,
+						ResourceID: key.id,
+						OrderID:    other.order.ID,
+						Status:     other.order.Status,
+						OrderTime:  other.order.OrderTime,
+						EndTime:    *other.order.EndTime,
+					})
+				}
+			}
+		}
+
+		if len(messages) > 0 && !override {
+			row.Valid = false
+			row.Errors = append(row.Errors, messages...)
+			continue
+		}
+		row.Warnings = append(row.Warnings, messages...)
+		if order.EndTime != nil {
+			for _, key := range bookingKeys(order) {
+				booked[key] = append(booked[key], booking{order, fmt.Sprintf("row %d", row.Row)})
+			}
+		}
+		validOrders = append(validOrders, *order)
+		validRows = append(validRows, orderRows[i])
+		validConflicts = append(validConflicts, conflicts[i])
+	}
+	return validOrders, validRows, validConflicts, nil
+}
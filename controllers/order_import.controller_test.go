@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestCheckImportConflicts(t *testing.T) {
+	// A dry run finds no saved orders, only the rows can conflict
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	driver := uuid.New()
+	truck := uuid.New()
+	morning := time.Date(2024, 3, 15, 8, 0, 0, 0, time.Local)
+	newOrder := func(start time.Time, driverID *uuid.UUID, truckID *uuid.UUID) models.Order {
+		end := start.Add(4 * time.Hour)
+		return models.Order{ID: uuid.New(), Status: models.OrderStatusDraft, OrderTime: start, EndTime: &end, DriverID: driverID, TruckID: truckID}
+	}
+	sheet := func() ([]OrderImportRow, []models.Order, []int) {
+		orders := []models.Order{
+			newOrder(morning, &driver, &truck),
+			newOrder(morning.Add(2*time.Hour), &driver, nil),
+			newOrder(morning.Add(3*time.Hour), nil, &truck),
+			newOrder(morning.Add(4*time.Hour), &driver, &truck),
+		}
+		report := []OrderImportRow{{Row: 2, Valid: true}, {Row: 3, Valid: true}, {Row: 4, Valid: true}, {Row: 5, Valid: true}}
+		return report, orders, []int{0, 1, 2, 3}
+	}
+
+	t.Run("conflicting rows are invalid", func(t *testing.T) {
+		report, orders, orderRows := sheet()
+		orders, orderRows, conflicts, err := checkImportConflicts(db, report, orders, orderRows, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orders) != 2 || orderRows[0] != 0 || orderRows[1] != 3 {
+			t.Fatalf("got rows %v, want the first and the last", orderRows)
+		}
+		for _, row := range []int{1, 2} {
+			if report[row].Valid || len(report[row].Errors) != 1 {
+				t.Errorf("row %d: got valid %v and errors %v", report[row].Row, report[row].Valid, report[row].Errors)
+			}
+		}
+		for i := range conflicts {
+			if len(conflicts[i]) > 0 {
+				t.Errorf("valid order %d has conflicts %v", i, conflicts[i])
+			}
+		}
+	})
+
+	t.Run("conflicting rows are kept when overridden", func(t *testing.T) {
+		report, orders, orderRows := sheet()
+		first := orders[0].ID
+		orders, _, conflicts, err := checkImportConflicts(db, report, orders, orderRows, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orders) != 4 {
+			t.Fatalf("got %d orders, want 4", len(orders))
+		}
+		for i, want := range []int{0, 1, 1, 2} {
+			if len(conflicts[i]) != want || len(report[i].Warnings) != want || !report[i].Valid {
+				t.Errorf("row %d: got %d conflicts and warnings %v, want %d", report[i].Row, len(conflicts[i]), report[i].Warnings, want)
+			}
+		}
+		if conflicts[1][0].OrderID != first || conflicts[1][0].Resource != models.ResourceDriver {
+			t.Errorf("got conflict %+v, want the driver of the first row", conflicts[1][0])
+		}
+	})
+}
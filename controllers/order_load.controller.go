@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"gorm.io/gorm"
+)
+
+// checkTruckLoad checks the order's load against its truck. It writes the
+// error response itself, 422 when the truck would be overloaded, and
+// returns the warnings of the check otherwise.
+func (ctrl *OrderController) checkTruckLoad(ctx *gin.Context, order *models.Order) ([]string, bool) {
+	if order.TruckID == nil || *order.TruckID == uuid.Nil {
+		return nil, true
+	}
+
+	var truck models.Truck
+	if err := ctrl.DB.First(&truck, "id = ?", *order.TruckID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "No truck with that ID exists"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve the truck"})
+		}
+		return nil, false
+	}
+
+	check := order.CheckTruckLoad(&truck)
+	if check.Overloaded() {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"status": "fail", "message": "The truck would be overloaded: " + strings.Join(check.Errors, "; "), "data": check})
+		return nil, false
+	}
+	return check.Warnings, true
+}
+
+// OrderLoadReport is an order that exceeded its truck's capacity or volume
+type OrderLoadReport struct {
+	Order models.Order     `json:"order"`
+	Check models.LoadCheck `json:"check"`
+}
+
+// GetOverloadReport lists the orders of a month, by default last month,
+// whose load exceeded the capacity or volume of their truck. month and year
+// pick another month. Cancelled orders are left out.
+func (ctrl *OrderController) GetOverloadReport(ctx *gin.Context) {
+	month, year, ok := periodQuery(ctx)
+	if !ok {
+		return
+	}
+	if month == 0 {
+		// Step back from the first of the month, the 31st minus a month can
+		// still be in the current month
+		now := time.Now()
+		lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0)
+		month, year = int(lastMonth.Month()), lastMonth.Year()
+	}
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+
+	var orders []models.Order
+	if err := ctrl.DB.
+		Preload("Contractor").
+		Preload("Driver").
+		Preload("Truck").
+		Preload("Client").
+		Where("order_time >= ? AND order_time < ?", start, start.AddDate(0, 1, 0)).
+		Where("truck_id IS NOT NULL AND status <> ?", models.OrderStatusCancelled).
+		Where("(package_weight > 0 OR package_volume > 0)").
+		Order("order_time").
+		Find(&orders).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve orders"})
+		return
+	}
+
+	canReadPricing := middleware.HasPermission(ctx, models.PermPricingRead)
+	canReadSalary := middleware.HasPermission(ctx, models.PermSalaryRead)
+	reports := []OrderLoadReport{}
+	for _, order := range orders {
+		check := order.CheckTruckLoad(&order.Truck)
+		if !check.Overloaded() {
+			continue
+		}
+		if !canReadPricing {
+			order.HidePricing()
+		}
+		if !canReadSalary {
+			order.HideSalary()
+		}
+		reports = append(reports, OrderLoadReport{Order: order, Check: check})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"month":   month,
+		"year":    year,
+		"checked": len(orders),
+		"results": len(reports),
+		"data":    reports,
+	})
+}
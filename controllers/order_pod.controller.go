@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"gorm.io/gorm"
+)
+
+const (
+	maxPODUploadSize = 50 << 20
+	maxPODPhotos     = 10
+)
+
+// orderPODsLatestFirst orders proofs of delivery by delivery, the latest first
+const orderPODsLatestFirst = "delivered_at DESC, created_at DESC"
+
+// OrderPODController records proofs of delivery. Their photos and signature
+// images are kept by the file storage of FileController.
+type OrderPODController struct {
+	DB    *gorm.DB
+	Files FileController
+}
+
+func NewOrderPODController(DB *gorm.DB, files FileController) OrderPODController {
+	return OrderPODController{DB, files}
+}
+
+// CreatePOD records a proof of delivery of an order. It is a multipart form
+// with receiver_name, delivered_at (RFC 3339, now when empty) and notes,
+// up to 10 images in the photos field and one image in the signature field.
+// At least one photo or a signature is required.
+func (pc *OrderPODController) CreatePOD(ctx *gin.Context) {
+	pc.createPOD(ctx, nil)
+}
+
+// CreateDriverPOD records a proof of delivery of one of the current
+// driver's orders, see CreatePOD
+func (pc *OrderPODController) CreateDriverPOD(ctx *gin.Context) {
+	driverID := ctx.MustGet("currentDriverID").(uuid.UUID)
+	pc.createPOD(ctx, &driverID)
+}
+
+func (pc *OrderPODController) createPOD(ctx *gin.Context, driverID *uuid.UUID) {
+	id := ctx.Param("orderId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order ID format"})
+		return
+	}
+
+	query := pc.DB.Where("id = ?", id)
+	if driverID != nil {
+		query = query.Where("driver_id = ?", *driverID)
+	}
+	var order models.Order
+	if err := query.First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Order not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order"})
+		}
+		return
+	}
+	if order.Status == models.OrderStatusDraft || order.Status == models.OrderStatusCancelled {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "A proof of delivery cannot be added to a " + order.Status + " order"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPODUploadSize)
+	var payload models.CreateOrderPODInput
+	if err := ctx.ShouldBind(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Send the proof of delivery as a multipart form of at most 50 MB"})
+		return
+	}
+
+	photos, signatures := form.File["photos"], form.File["signature"]
+	switch {
+	case len(photos) == 0 && len(signatures) == 0:
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Add at least one photo or a signature"})
+		return
+	case len(photos) > maxPODPhotos:
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "At most 10 photos can be added"})
+		return
+	case len(signatures) > 1:
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Only one signature can be added"})
+		return
+	}
+
+	pod := models.OrderPOD{
+		ID:           uuid.New(),
+		OrderID:      order.ID,
+		ReceiverName: payload.ReceiverName,
+		DeliveredAt:  time.Now(),
+		Notes:        payload.Notes,
+		CreatedAt:    time.Now(),
+	}
+	if payload.DeliveredAt != nil {
+		pod.DeliveredAt = *payload.DeliveredAt
+	}
+	if value, exists := ctx.Get("currentUser"); exists {
+		user := value.(models.User)
+		pod.RecordedByID = &user.ID
+		pod.RecordedBy = user.Email
+	}
+
+	// Stored files are removed again when the record cannot be saved
+	removeFiles := func() {
+		for _, file := range pod.Files {
+			pc.Files.RemoveFile(file.FileName)
+		}
+	}
+	for _, upload := range []struct {
+		kind  string
+		files []*multipart.FileHeader
+	}{
+		{models.PODFilePhoto, photos},
+		{models.PODFileSignature, signatures},
+	} {
+		for _, fileHeader := range upload.files {
+			name, contentType, err := pc.Files.SaveFile(fileHeader, "image/")
+			if err != nil {
+				removeFiles()
+				if errors.Is(err, errFileType) {
+					ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": fileHeader.Filename + " is not an image"})
+				} else {
+					ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to store the proof of delivery files"})
+				}
+				return
+			}
+			pod.Files = append(pod.Files, models.OrderPODFile{
+				ID:           uuid.New(),
+				PODID:        pod.ID,
+				Kind:         upload.kind,
+				FileName:     name,
+				OriginalName: fileHeader.Filename,
+				ContentType:  contentType,
+				Size:         fileHeader.Size,
+				CreatedAt:    time.Now(),
+			})
+		}
+	}
+
+	if err := pc.DB.WithContext(ctx).Create(&pod).Error; err != nil {
+		removeFiles()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to save the proof of delivery"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": pod})
+}
+
+// GetPODs lists the proofs of delivery of an order, the latest delivery first
+func (pc *OrderPODController) GetPODs(ctx *gin.Context) {
+	id := ctx.Param("orderId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order ID format"})
+		return
+	}
+
+	var pods []models.OrderPOD
+	if err := pc.DB.Preload("Files").Where("order_id = ?", id).Order(orderPODsLatestFirst).Find(&pods).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve proofs of delivery"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(pods), "data": pods})
+}
+
+// orderPODsInDeliveryOrder preloads an order's proofs of delivery, the
+// latest first
+func orderPODsInDeliveryOrder(db *gorm.DB) *gorm.DB {
+	return db.Order(orderPODsLatestFirst)
+}
+
+// orderPODMissing reports whether the order's client requires a proof of
+// delivery and none has been recorded for the order yet
+func orderPODMissing(db *gorm.DB, order *models.Order) (bool, error) {
+	var client models.Client
+	if err := db.Unscoped().Select("id", "requires_pod").First(&client, "id = ?", order.ClientID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	if !client.RequiresPOD {
+		return false, nil
+	}
+
+	var count int64
+	if err := db.Model(&models.OrderPOD{}).Where("order_id = ?", order.ID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+// busyOrders selects the orders that keep their driver and truck busy at
+// some point between start and end. Cancelled orders free them.
+func busyOrders(db *gorm.DB, start time.Time, end time.Time) *gorm.DB {
+	return db.Model(&models.Order{}).
+		Where("status <> ?", models.OrderStatusCancelled).
+		Where("order_time < ? AND end_time > ?", end, start)
+}
+
+// findAssignmentConflicts returns the other orders that have the order's
+// driver or truck in an overlapping time window. ApplyEndTime must have run.
+func findAssignmentConflicts(db *gorm.DB, order *models.Order) ([]models.OrderAssignmentConflict, error) {
+	var conflicts []models.OrderAssignmentConflict
+	for _, resource := range []struct {
+		name   string
+		column string
+		id     *uuid.UUID
+	}{
+		{models.ResourceDriver, "driver_id", order.DriverID},
+		{models.ResourceTruck, "truck_id", order.TruckID},
+	} {
+		if resource.id == nil || *resource.id == uuid.Nil {
+			continue
+		}
+
+		var orders []models.Order
+		if err := busyOrders(db, order.OrderTime, *order.EndTime).
+			Select("id", "status", "order_time", "end_time").
+			Where("id <> ? AND "+resource.column+" = ?", order.ID, *resource.id).
+			Order("order_time").
+			Find(&orders).Error; err != nil {
+			return nil, err
+		}
+		for _, other := range orders {
+			conflict := models.OrderAssignmentConflict{
+				Resource:   resource.name,
+				ResourceID: *resource.id,
+				OrderID:    other.ID,
+				Status:     other.Status,
+				OrderTime:  other.OrderTime,
+			}
+			if other.EndTime != nil {
+				conflict.EndTime = *other.EndTime
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts, nil
+}
+
+// checkAssignment rejects an order that double-books its driver or truck,
+// unless the force query is true and the caller may override the check. It
+// writes the error response itself and returns the overridden conflicts,
+// which the caller records with newAssignmentOverrides.
+func (ctrl *OrderController) checkAssignment(ctx *gin.Context, order *models.Order) ([]models.OrderAssignmentConflict, bool) {
+	conflicts, err := findAssignmentConflicts(ctrl.DB, order)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to check the driver and truck schedule"})
+		return nil, false
+	}
+	if len(conflicts) == 0 {
+		return nil, true
+	}
+
+	if ctx.Query("force") != "true" {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"status":    "fail",
+			"message":   "The driver or truck is already booked by another order in this time window",
+			"conflicts": conflicts,
+		})
+		return nil, false
+	}
+	if !middleware.HasPermission(ctx, models.PermOrdersOverride) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"status":    "fail",
+			"message":   "You are not allowed to double-book a driver or truck",
+			"conflicts": conflicts,
+		})
+		return nil, false
+	}
+	return conflicts, true
+}
+
+// newAssignmentOverrides records who saved the order despite its conflicts
+func newAssignmentOverrides(ctx *gin.Context, orderID uuid.UUID, conflicts []models.OrderAssignmentConflict) []models.OrderAssignmentOverride {
+	overrides := make([]models.OrderAssignmentOverride, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		override := models.OrderAssignmentOverride{
+			ID:                 uuid.New(),
+			OrderID:            orderID,
+			ConflictingOrderID: conflict.OrderID,
+			Resource:           conflict.Resource,
+			ResourceID:         conflict.ResourceID,
+			CreatedAt:          time.Now(),
+		}
+		if value, exists := ctx.Get("currentUser"); exists {
+			user := value.(models.User)
+			override.OverriddenByID = &user.ID
+			override.OverriddenBy = user.Email
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides
+}
+
+// GetAvailability lists the drivers and active trucks that no order keeps
+// busy between from and to (YYYY-MM-DD or RFC 3339, a date as to includes
+// the whole day). contractor_id only lists those of one contractor.
+func (ctrl *OrderController) GetAvailability(ctx *gin.Context) {
+	start, end, err := utils.ParseTimeWindow(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	drivers, err := utils.NewQueryFilter(ctrl.DB.Model(&models.Driver{})).
+		Where("id NOT IN (?)", busyOrders(ctrl.DB, start, end).Select("driver_id").Where("driver_id IS NOT NULL")).
+		UUID("contractor_id", ctx.Query("contractor_id"), "contractor_id").
+		Query()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	trucks, err := utils.NewQueryFilter(ctrl.DB.Model(&models.Truck{})).
+		Where("status = ?", "active").
+		Where("id NOT IN (?)", busyOrders(ctrl.DB, start, end).Select("truck_id").Where("truck_id IS NOT NULL")).
+		UUID("contractor_id", ctx.Query("contractor_id"), "contractor_id").
+		Query()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var freeDrivers []models.Driver
+	if err := drivers.Order("full_name").Find(&freeDrivers).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve available drivers"})
+		return
+	}
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		for i := range freeDrivers {
+			freeDrivers[i].HideSalary()
+		}
+	}
+	var freeTrucks []models.Truck
+	if err := trucks.Order("license_plate").Find(&freeTrucks).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve available trucks"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"from":   start,
+		"to":     end,
+		"data":   gin.H{"drivers": freeDrivers, "trucks": freeTrucks},
+	})
+}
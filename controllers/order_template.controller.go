@@ -0,0 +1,591 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+// OrderTemplateController manages recurring bookings and the holidays they
+// skip. Its scheduler creates the draft orders of the templates ahead of
+// time and logs every day it booked as an OrderTemplateRun.
+type OrderTemplateController struct {
+	DB     *gorm.DB
+	Orders OrderController
+}
+
+func NewOrderTemplateController(DB *gorm.DB, orders OrderController) OrderTemplateController {
+	return OrderTemplateController{DB, orders}
+}
+
+// CreateTemplate creates an order template. Its orders are booked from the
+// next scheduler run on.
+func (tc *OrderTemplateController) CreateTemplate(ctx *gin.Context) {
+	var template models.OrderTemplate
+	if err := ctx.ShouldBindJSON(&template); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	// Driver pay is only set by roles with access to payroll data
+	if !middleware.HasPermission(ctx, models.PermSalaryWrite) {
+		template.HideSalary()
+	}
+	if err := template.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	template.ID = uuid.New()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	template.DeletedAt = gorm.DeletedAt{}
+
+	if err := tc.DB.WithContext(ctx).Create(&template).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create order template"})
+		return
+	}
+
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		template.HideSalary()
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": template})
+}
+
+// GetTemplates lists the order templates by name. Filters: client_id,
+// contractor_id and paused. Paging: page and limit.
+func (tc *OrderTemplateController) GetTemplates(ctx *gin.Context) {
+	query, err := utils.NewQueryFilter(tc.DB.Model(&models.OrderTemplate{})).
+		UUID("client_id", ctx.Query("client_id"), "client_id").
+		UUID("contractor_id", ctx.Query("contractor_id"), "contractor_id").
+		OneOf("paused", ctx.Query("paused"), "paused", func(value string) bool {
+			return value == "true" || value == "false"
+		}).
+		Query()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 50, 200)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order templates"})
+		return
+	}
+
+	var templates []models.OrderTemplate
+	if err := query.Session(&gorm.Session{}).
+		Preload("Client").
+		Preload("Contractor").
+		Order("name").Limit(page.Limit).Offset(page.Offset).
+		Find(&templates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order templates"})
+		return
+	}
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		for i := range templates {
+			templates[i].HideSalary()
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"total":  total,
+		"page":   page.Page,
+		"limit":  page.Limit,
+		"data":   templates,
+	})
+}
+
+// GetTemplate retrieves an order template by ID
+func (tc *OrderTemplateController) GetTemplate(ctx *gin.Context) {
+	id := ctx.Param("templateId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order template ID format"})
+		return
+	}
+
+	var template models.OrderTemplate
+	if err := tc.DB.Preload("Client").Preload("Contractor").First(&template, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Order template not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order template"})
+		}
+		return
+	}
+
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		template.HideSalary()
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": template})
+}
+
+// UpdateTemplate updates the sent fields of an order template. Orders that
+// were already booked keep the values they were created with.
+func (tc *OrderTemplateController) UpdateTemplate(ctx *gin.Context) {
+	id := ctx.Param("templateId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order template ID format"})
+		return
+	}
+
+	var template models.OrderTemplate
+	if err := tc.DB.First(&template, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Order template not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve order template"})
+		}
+		return
+	}
+
+	if err := ctx.ShouldBindBodyWith(&template, binding.JSON); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	columns, err := utils.SentColumns(tc.DB, &template, ctx.MustGet(gin.BodyBytesKey).([]byte),
+		"id", "created_at", "deleted_at")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Send the order template fields as a JSON object"})
+		return
+	}
+	// Sent driver pay is not saved without access to payroll data
+	if !middleware.HasPermission(ctx, models.PermSalaryWrite) {
+		kept := columns[:0]
+		for _, column := range columns {
+			if !isOrderSalaryDBColumn(column) {
+				kept = append(kept, column)
+			}
+		}
+		columns = kept
+	}
+	template.ID = uuid.MustParse(id)
+	if err := template.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := tc.DB.WithContext(ctx).Model(&template).Select(append(columns, "updated_at")).Updates(&template).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update order template"})
+		return
+	}
+
+	if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+		template.HideSalary()
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": template})
+}
+
+// DeleteTemplate stops an order template. Orders it already booked are kept.
+func (tc *OrderTemplateController) DeleteTemplate(ctx *gin.Context) {
+	id := ctx.Param("templateId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid order template ID format"})
+		return
+	}
+
+	result := tc.DB.WithContext(ctx).Delete(&models.OrderTemplate{}, "id = ?", id)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to delete order template"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Order template not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// GetTemplateRuns lists what the scheduler did for each day of the
+// templates, the latest day first. Filters: template_id, result and the
+// from and to days. Paging: page and limit.
+func (tc *OrderTemplateController) GetTemplateRuns(ctx *gin.Context) {
+	query, err := utils.NewQueryFilter(tc.DB.Model(&models.OrderTemplateRun{})).
+		UUID("template_id", ctx.Query("template_id"), "template_id").
+		OneOf("result", ctx.Query("result"), "result", func(result string) bool {
+			return result == models.TemplateRunCreated || result == models.TemplateRunHoliday || result == models.TemplateRunFailed
+		}).
+		TimeRange("date", ctx.Query("from"), ctx.Query("to")).
+		Query()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 100, 500)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve the order template log"})
+		return
+	}
+
+	var runs []models.OrderTemplateRun
+	if err := query.Session(&gorm.Session{}).
+		Order("date DESC, created_at DESC").Limit(page.Limit).Offset(page.Offset).
+		Find(&runs).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve the order template log"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"total":  total,
+		"page":   page.Page,
+		"limit":  page.Limit,
+		"data":   runs,
+	})
+}
+
+// GenerateTemplateOrders runs the scheduler now instead of waiting for its
+// next run, and returns the days it booked
+func (tc *OrderTemplateController) GenerateTemplateOrders(ctx *gin.Context) {
+	runs, err := tc.GenerateOrders(ctx, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to generate some orders, the others were created", "data": runs})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(runs), "data": runs})
+}
+
+// StartScheduler books the orders of the templates in the background, at
+// start and then every interval, until ctx is cancelled. An interval of 0
+// leaves the scheduler off, for servers that share the database with one
+// that runs it.
+func (tc *OrderTemplateController) StartScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := tc.GenerateOrders(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Println("Failed to generate orders from templates:", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// GenerateOrders books the days of the active templates from today up to
+// their lead time. Days with an order are skipped, failed days and former
+// holidays are tried again, so running it again or on several servers
+// books each day once. It returns the new runs and the last error, the
+// other days and templates are still booked when one fails.
+func (tc *OrderTemplateController) GenerateOrders(ctx context.Context, now time.Time) ([]models.OrderTemplateRun, error) {
+	today := models.DateOf(now)
+
+	var templates []models.OrderTemplate
+	if err := tc.DB.Where("paused = ? AND (end_date IS NULL OR end_date >= ?)", false, today).
+		Order("created_at").
+		Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	last := today
+	for _, template := range templates {
+		if end := today.AddDays(template.LeadDays); end.After(last.Time) {
+			last = end
+		}
+	}
+	var holidayList []models.Holiday
+	if err := tc.DB.Where("date BETWEEN ? AND ?", today, last).Find(&holidayList).Error; err != nil {
+		return nil, err
+	}
+	holidays := make(map[string]string, len(holidayList))
+	for _, holiday := range holidayList {
+		holidays[holiday.Date.String()] = holiday.Name
+	}
+
+	var runs []models.OrderTemplateRun
+	var lastErr error
+	for i := range templates {
+		templateRuns, err := tc.generateTemplateOrders(ctx, &templates[i], today, holidays)
+		runs = append(runs, templateRuns...)
+		if err != nil {
+			log.Printf("Failed to generate orders from template %s: %v", templates[i].ID, err)
+			lastErr = err
+		}
+	}
+	return runs, lastErr
+}
+
+// generateTemplateOrders books the days of one template from today up to its
+// lead time that are not booked yet
+func (tc *OrderTemplateController) generateTemplateOrders(ctx context.Context, template *models.OrderTemplate, today models.Date, holidays map[string]string) ([]models.OrderTemplateRun, error) {
+	last := today.AddDays(template.LeadDays)
+
+	var done []models.OrderTemplateRun
+	if err := tc.DB.Select("date", "result").
+		Where("template_id = ? AND date BETWEEN ? AND ? AND result <> ?", template.ID, today, last, models.TemplateRunFailed).
+		Find(&done).Error; err != nil {
+		return nil, err
+	}
+	results := make(map[string]string, len(done))
+	for _, run := range done {
+		results[run.Date.String()] = run.Result
+	}
+
+	var runs []models.OrderTemplateRun
+	var lastErr error
+	for day := today; !day.After(last.Time); day = day.AddDays(1) {
+		if !template.OccursOn(day) {
+			continue
+		}
+		_, holiday := holidays[day.String()]
+		if result := results[day.String()]; result == models.TemplateRunCreated || (result == models.TemplateRunHoliday && holiday) {
+			continue
+		}
+
+		run := models.OrderTemplateRun{
+			ID:         uuid.New(),
+			TemplateID: template.ID,
+			Date:       day,
+			CreatedAt:  time.Now(),
+		}
+		var err error
+		if name, ok := holidays[day.String()]; ok {
+			run.Result = models.TemplateRunHoliday
+			run.Message = name
+			err = tc.recordRun(ctx, &run, nil)
+		} else {
+			err = tc.bookDay(ctx, template, &run)
+		}
+		switch {
+		case errors.Is(err, errTemplateDayBooked):
+			// Another server booked the day in the meantime
+		case err != nil:
+			lastErr = err
+		default:
+			runs = append(runs, run)
+		}
+	}
+	return runs, lastErr
+}
+
+// bookDay creates the draft order of the template for the run's day. A
+// default driver or truck that is booked by another order, or that cannot
+// carry the load, is left off the order and noted in the run's message. An
+// order that cannot be created is logged as a failed run.
+func (tc *OrderTemplateController) bookDay(ctx context.Context, template *models.OrderTemplate, run *models.OrderTemplateRun) error {
+	fail := func(message string) error {
+		run.Result = models.TemplateRunFailed
+		run.Message = message
+		return tc.recordRun(ctx, run, nil)
+	}
+
+	order := template.NewOrder(run.Date, time.Local)
+	if err := order.ApplyEndTime(); err != nil {
+		return fail(err.Error())
+	}
+
+	var notes []string
+	if order.TruckID != nil {
+		var truck models.Truck
+		if err := tc.DB.First(&truck, "id = ?", *order.TruckID).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+			order.TruckID = nil
+			notes = append(notes, "truck left unassigned, it no longer exists")
+		} else if check := order.CheckTruckLoad(&truck); check.Overloaded() {
+			order.TruckID = nil
+			notes = append(notes, "truck left unassigned, "+strings.Join(check.Errors, "; "))
+		}
+	}
+
+	conflicts, err := findAssignmentConflicts(tc.DB, &order)
+	if err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		switch {
+		case conflict.Resource == models.ResourceDriver && order.DriverID != nil:
+			order.DriverID = nil
+		case conflict.Resource == models.ResourceTruck && order.TruckID != nil:
+			order.TruckID = nil
+		default:
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("%s left unassigned, it is booked by order %s", conflict.Resource, conflict.OrderID))
+	}
+
+	total, err := order.CalculateTotalSalary()
+	if err != nil {
+		return fail(err.Error())
+	}
+	order.OrderType = total.OrderType
+	order.TotalSalary = &total.TotalSalary
+
+	pricing, err := tc.Orders.priceOrder(&order)
+	if err != nil {
+		return err
+	}
+	if !pricing.Client.Matched {
+		notes = append(notes, "client price: "+pricing.Client.Reason)
+	}
+	if !pricing.Contractor.Matched {
+		notes = append(notes, "contractor price: "+pricing.Contractor.Reason)
+	}
+
+	history := &models.OrderStatusHistory{
+		ID:        uuid.New(),
+		OrderID:   order.ID,
+		ToStatus:  order.Status,
+		Note:      "Created from order template " + template.Name,
+		CreatedAt: time.Now(),
+	}
+	if user, ok := ctx.Value("currentUser").(models.User); ok {
+		history.ChangedByID = &user.ID
+		history.ChangedBy = user.Email
+	}
+
+	run.Result = models.TemplateRunCreated
+	run.OrderID = &order.ID
+	run.Message = strings.Join(notes, "; ")
+	return tc.recordRun(ctx, run, func(tx *gorm.DB) error {
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		return tx.Create(history).Error
+	})
+}
+
+// recordRun saves the run together with what book writes. It replaces a
+// failed or holiday run of the same day, a run that created an order stays
+// and its unique day keeps two servers from booking the day twice. The
+// server that loses gets errTemplateDayBooked.
+func (tc *OrderTemplateController) recordRun(ctx context.Context, run *models.OrderTemplateRun, book func(tx *gorm.DB) error) error {
+	return tc.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ? AND date = ? AND result <> ?", run.TemplateID, run.Date, models.TemplateRunCreated).
+			Delete(&models.OrderTemplateRun{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(run).Error; err != nil {
+			if strings.Contains(err.Error(), "duplicate key value violates unique") {
+				return errTemplateDayBooked
+			}
+			return err
+		}
+		if book == nil {
+			return nil
+		}
+		return book(tx)
+	})
+}
+
+var errTemplateDayBooked = errors.New("the template day is already booked")
+
+// GetHolidays lists the holidays by date, year picks the holidays of one year
+func (tc *OrderTemplateController) GetHolidays(ctx *gin.Context) {
+	query := tc.DB.Order("date")
+	if year := ctx.Query("year"); year != "" {
+		start, err := models.ParseDate(year + "-01-01")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid year"})
+			return
+		}
+		query = query.Where("date >= ? AND date < ?", start, models.Date{Time: start.AddDate(1, 0, 0)})
+	}
+
+	var holidays []models.Holiday
+	if err := query.Find(&holidays).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve holidays"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(holidays), "data": holidays})
+}
+
+// CreateHoliday adds a day no template orders are booked on. Orders that
+// were already booked for the day are kept.
+func (tc *OrderTemplateController) CreateHoliday(ctx *gin.Context) {
+	var payload models.CreateHolidayInput
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var existing int64
+	tc.DB.Model(&models.Holiday{}).Where("date = ?", payload.Date).Count(&existing)
+	if existing > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "There already is a holiday on " + payload.Date.String()})
+		return
+	}
+
+	holiday := models.Holiday{
+		ID:        uuid.New(),
+		Date:      payload.Date,
+		Name:      payload.Name,
+		CreatedAt: time.Now(),
+	}
+	if err := tc.DB.WithContext(ctx).Create(&holiday).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create holiday"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": holiday})
+}
+
+// DeleteHoliday removes a holiday, the templates book the day from the next
+// scheduler run on
+func (tc *OrderTemplateController) DeleteHoliday(ctx *gin.Context) {
+	id := ctx.Param("holidayId")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid holiday ID format"})
+		return
+	}
+
+	var holiday models.Holiday
+	if err := tc.DB.First(&holiday, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Holiday not found"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve holiday"})
+		}
+		return
+	}
+
+	if err := tc.DB.WithContext(ctx).Delete(&holiday).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to delete holiday"})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
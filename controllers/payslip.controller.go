@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
 	"gorm.io/gorm"
 )
 
@@ -27,7 +30,7 @@ func (ctrl *PayslipController) CreatePayslip(ctx *gin.Context) {
 	}
 
 	newPayslip.ID = uuid.New() // Generate a new UUID for the payslip
-	if err := ctrl.DB.Create(&newPayslip).Error; err != nil {
+	if err := ctrl.DB.WithContext(ctx).Create(&newPayslip).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
 		return
 	}
@@ -113,10 +116,14 @@ func (ctrl *PayslipController) GetPayslipByID(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("ETag", utils.ETag(payslip.Version))
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": payslip})
 }
 
-// UpdatePayslip updates an existing payslip
+// UpdatePayslip updates the sent fields of an existing payslip. The update
+// is rejected with 409 and the current payslip when the payslip changed
+// since the version in the If-Match header, or the version field, was read,
+// and with 428 when neither is sent.
 func (ctrl *PayslipController) UpdatePayslip(ctx *gin.Context) {
 	id := ctx.Param("payslipId")
 	var payslip models.Payslip
@@ -135,24 +142,73 @@ func (ctrl *PayslipController) UpdatePayslip(ctx *gin.Context) {
 		return
 	}
 
-	if err := ctx.ShouldBindJSON(&payslip); err != nil {
+	stored := payslip.Version
+
+	if err := ctx.ShouldBindBodyWith(&payslip, binding.JSON); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
 		return
 	}
+	columns, err := utils.SentColumns(ctrl.DB, &payslip, ctx.MustGet(gin.BodyBytesKey).([]byte),
+		"id", "created_at", "deleted_at", "version")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Send the payslip fields as a JSON object"})
+		return
+	}
 
-	if err := ctrl.DB.Save(&payslip).Error; err != nil {
+	expected, err := utils.ExpectedVersion(ctx.GetHeader("If-Match"), utils.SentVersion(ctx.MustGet(gin.BodyBytesKey).([]byte)))
+	if errors.Is(err, utils.ErrVersionRequired) {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"status": "fail", "message": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if expected != stored {
+		ctrl.payslipVersionConflict(ctx, id)
+		return
+	}
+	payslip.ID = uuid.MustParse(id)
+	payslip.Version = expected + 1
+
+	result := ctrl.DB.WithContext(ctx).Model(&payslip).
+		Where("version = ?", expected).
+		Select(append(columns, "version", "updated_at")).
+		Updates(&payslip)
+	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update payslip"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		ctrl.payslipVersionConflict(ctx, id)
+		return
+	}
 
+	ctx.Header("ETag", utils.ETag(payslip.Version))
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": payslip})
 }
 
-// DeletePayslip deletes a payslip by ID
+// payslipVersionConflict rejects an update of a payslip that was changed by
+// someone else since, with the current payslip to apply the changes to again
+func (ctrl *PayslipController) payslipVersionConflict(ctx *gin.Context, id string) {
+	var current models.Payslip
+	if err := ctrl.DB.First(&current, "id = ?", id).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve payslip"})
+		return
+	}
+
+	ctx.Header("ETag", utils.ETag(current.Version))
+	ctx.JSON(http.StatusConflict, gin.H{
+		"status":  "fail",
+		"message": "The payslip was changed by someone else, apply your changes to the current payslip and try again",
+		"data":    current,
+	})
+}
+
+// DeletePayslip moves a payslip to the trash, see TrashController
 func (ctrl *PayslipController) DeletePayslip(ctx *gin.Context) {
 	id := ctx.Param("payslipId")
 
-	if err := ctrl.DB.Delete(&models.Payslip{}, "id = ?", id).Error; err != nil {
+	if err := ctrl.DB.WithContext(ctx).Delete(&models.Payslip{}, "id = ?", id).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to delete payslip"})
 		return
 	}
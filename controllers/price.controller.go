@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
 	"gorm.io/gorm"
 )
 
@@ -39,7 +40,7 @@ func (pc *PricingController) CreatePricing(ctx *gin.Context) {
 		UpdatedAt:    time.Now(),
 	}
 
-	tx := pc.DB.Begin()
+	tx := pc.DB.WithContext(ctx).Begin()
 
 	if err := tx.Create(&pricing).Error; err != nil {
 		tx.Rollback()
@@ -104,6 +105,7 @@ func (pc *PricingController) FindLatestPricingByOwner(c *gin.Context) {
 	}
 
 	// Return the latest pricing
+	c.Header("ETag", utils.ETag(latestPricing.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "success", "data": latestPricing})
 }
 
@@ -131,14 +133,126 @@ func (pc *PricingController) FindPricingByOwnerAndPriceID(c *gin.Context) {
 	}
 
 	// Return the latest pricing
+	c.Header("ETag", utils.ETag(latestPricing.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "success", "data": latestPricing})
 }
 
+// UpdatePricing changes the file name and price details of a pricing. The
+// update is rejected with 409 and the current pricing when the pricing
+// changed since the version in the If-Match header, or the version field,
+// was read, and with 428 when neither is sent.
+func (pc *PricingController) UpdatePricing(ctx *gin.Context) {
+	ownerId := ctx.Param("ownerId")
+	priceID := ctx.Param("priceId")
+	if _, err := uuid.Parse(priceID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid pricing_id"})
+		return
+	}
+
+	var payload models.UpdatePricing
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var pricing models.Pricing
+	if err := pc.DB.Preload("PriceDetails").Where("owner_id = ? AND id = ?", ownerId, priceID).First(&pricing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Pricing not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	expected, err := utils.ExpectedVersion(ctx.GetHeader("If-Match"), payload.Version)
+	if errors.Is(err, utils.ErrVersionRequired) {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"status": "fail", "message": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if expected != pricing.Version {
+		pc.pricingVersionConflict(ctx, priceID)
+		return
+	}
+
+	columns := []string{"version", "updated_at"}
+	if payload.FileName != nil {
+		pricing.FileName = *payload.FileName
+		columns = append(columns, "file_name")
+	}
+	pricing.Version = expected + 1
+
+	if payload.Prices != nil {
+		kept := make(map[uuid.UUID]bool, len(pricing.PriceDetails))
+		for _, detail := range pricing.PriceDetails {
+			kept[detail.ID] = true
+		}
+		details := *payload.Prices
+		for i := range details {
+			if !kept[details[i].ID] {
+				details[i].ID = uuid.New()
+			}
+			details[i].PricingID = pricing.ID
+		}
+		pricing.PriceDetails = details
+	}
+
+	if err := pc.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&pricing).Where("version = ?", expected).Select(columns).Updates(&pricing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.ErrVersionConflict
+		}
+		if payload.Prices == nil {
+			return nil
+		}
+		if err := tx.Where("pricing_id = ?", pricing.ID).Delete(&models.PriceDetail{}).Error; err != nil {
+			return err
+		}
+		if len(pricing.PriceDetails) == 0 {
+			return nil
+		}
+		return tx.Create(&pricing.PriceDetails).Error
+	}); err != nil {
+		if errors.Is(err, utils.ErrVersionConflict) {
+			pc.pricingVersionConflict(ctx, priceID)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update pricing"})
+		}
+		return
+	}
+
+	ctx.Header("ETag", utils.ETag(pricing.Version))
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": pricing})
+}
+
+// pricingVersionConflict rejects an update of a pricing that was changed by
+// someone else since, with the current pricing to apply the changes to again
+func (pc *PricingController) pricingVersionConflict(ctx *gin.Context, id string) {
+	var current models.Pricing
+	if err := pc.DB.Preload("PriceDetails").First(&current, "id = ?", id).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve pricing"})
+		return
+	}
+
+	ctx.Header("ETag", utils.ETag(current.Version))
+	ctx.JSON(http.StatusConflict, gin.H{
+		"status":  "fail",
+		"message": "The pricing was changed by someone else, apply your changes to the current pricing and try again",
+		"data":    current,
+	})
+}
+
 // DeletePricingByContractorID deletes pricings and their price details for a specific contractor
 func (pc *PricingController) DeleteAllPricingByContractorID(ctx *gin.Context) {
 	ownerId := ctx.Param("ownerId")
 
-	tx := pc.DB.Begin()
+	tx := pc.DB.WithContext(ctx).Begin()
 
 	if err := tx.Where("owner_id = ?", ownerId).Delete(&models.PriceDetail{}).Error; err != nil {
 		tx.Rollback()
@@ -175,7 +289,7 @@ func (pc *PricingController) DeletePricingWithDetails(ctx *gin.Context) {
 		return
 	}
 
-	tx := pc.DB.Begin()
+	tx := pc.DB.WithContext(ctx).Begin()
 
 	// Check if the Pricing exists with the given owner_id and pricing_id
 	var pricing models.Pricing
@@ -1,12 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
 	"gorm.io/gorm"
 )
 
@@ -18,6 +20,10 @@ func NewSettingController(DB *gorm.DB) SettingController {
 	return SettingController{DB: DB}
 }
 
+// UpdateSetting creates the settings or replaces the stored ones. Replacing
+// is rejected with 409 and the current settings when they changed since the
+// version in the If-Match header, or the version field, was read, and with
+// 428 when neither is sent.
 func (sc *SettingController) UpdateSetting(ctx *gin.Context) {
 	var payload *models.Setting
 
@@ -39,7 +45,7 @@ func (sc *SettingController) UpdateSetting(ctx *gin.Context) {
 			UpdatedAt: now,
 		}
 
-		if err := sc.DB.Create(&newSetting).Error; err != nil {
+		if err := sc.DB.WithContext(ctx).Create(&newSetting).Error; err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
 			return
 		}
@@ -48,15 +54,44 @@ func (sc *SettingController) UpdateSetting(ctx *gin.Context) {
 		return
 	}
 
+	expected, err := utils.ExpectedVersion(ctx.GetHeader("If-Match"), payload.Version)
+	if errors.Is(err, utils.ErrVersionRequired) {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"status": "fail", "message": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
 	// If found, update the existing setting
 	setting.Settings = payload.Settings
 	setting.UpdatedAt = now
+	setting.Version = expected + 1
 
-	if err := sc.DB.Save(&setting).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+	result := sc.DB.WithContext(ctx).Model(&setting).
+		Where("version = ?", expected).
+		Select("settings", "version", "updated_at").
+		Updates(&setting)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		var current models.Setting
+		if err := sc.DB.First(&current, "id = ?", setting.ID).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+			return
+		}
+		ctx.Header("ETag", utils.ETag(current.Version))
+		ctx.JSON(http.StatusConflict, gin.H{
+			"status":  "fail",
+			"message": "The settings were changed by someone else, apply your changes to the current settings and try again",
+			"data":    current,
+		})
 		return
 	}
 
+	ctx.Header("ETag", utils.ETag(setting.Version))
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": setting})
 }
 
@@ -69,5 +104,6 @@ func (sc *SettingController) GetSetting(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("ETag", utils.ETag(setting.Version))
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": setting})
 }
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+type SettlementController struct {
+	DB *gorm.DB
+}
+
+func NewSettlementController(DB *gorm.DB) SettlementController {
+	return SettlementController{DB}
+}
+
+// FindSettlements lists the contractors' answers to their monthly
+// settlements, newest month first. The contractor_id, status, month and year
+// queries narrow it down.
+func (sc *SettlementController) FindSettlements(ctx *gin.Context) {
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 200, 1000)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	query := sc.DB.Model(&models.Settlement{})
+	if contractorID := ctx.Query("contractor_id"); contractorID != "" {
+		if _, err := uuid.Parse(contractorID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid contractor ID format"})
+			return
+		}
+		query = query.Where("contractor_id = ?", contractorID)
+	}
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	month, year, ok := periodQuery(ctx)
+	if !ok {
+		return
+	}
+	if month > 0 {
+		query = query.Where("month = ? AND year = ?", month, year)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var settlements []models.Settlement
+	if err := query.Preload("Contractor").Order("year DESC, month DESC").
+		Limit(page.Limit).Offset(page.Offset).Find(&settlements).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve settlements"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(settlements), "total": total, "data": settlements})
+}
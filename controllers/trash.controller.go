@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/initializers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+	"gorm.io/gorm"
+)
+
+// trashEntity is a soft deleted model the trash can list, restore and purge
+type trashEntity struct {
+	model     func() interface{}
+	list      func() interface{}
+	readPerm  string
+	writePerm string
+	// purge deletes the rows that belong to the record and are not removed
+	// by a foreign key cascade
+	purge func(tx *gorm.DB, id string) error
+}
+
+// trashEntities are the trash types, named as in the URL
+var trashEntities = map[string]trashEntity{
+	"orders": {
+		model:     func() interface{} { return &models.Order{} },
+		list:      func() interface{} { return &[]models.Order{} },
+		readPerm:  models.PermOrdersRead,
+		writePerm: models.PermOrdersWrite,
+		purge: func(tx *gorm.DB, id string) error {
+			if err := tx.Where("order_id = ?", id).Delete(&models.OrderStatusHistory{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("order_id = ?", id).Delete(&models.OrderAssignmentOverride{}).Error
+		},
+	},
+	"payslips": {
+		model:     func() interface{} { return &models.Payslip{} },
+		list:      func() interface{} { return &[]models.Payslip{} },
+		readPerm:  models.PermPayslipsRead,
+		writePerm: models.PermPayslipsWrite,
+	},
+	"drivers": {
+		model:     func() interface{} { return &models.Driver{} },
+		list:      func() interface{} { return &[]models.Driver{} },
+		readPerm:  models.PermDriversRead,
+		writePerm: models.PermDriversWrite,
+	},
+	"trucks": {
+		model:     func() interface{} { return &models.Truck{} },
+		list:      func() interface{} { return &[]models.Truck{} },
+		readPerm:  models.PermTrucksRead,
+		writePerm: models.PermTrucksWrite,
+	},
+	"contractors": {
+		model:     func() interface{} { return &models.Contractor{} },
+		list:      func() interface{} { return &[]models.Contractor{} },
+		readPerm:  models.PermContractorsRead,
+		writePerm: models.PermContractorsWrite,
+	},
+	"clients": {
+		model:     func() interface{} { return &models.Client{} },
+		list:      func() interface{} { return &[]models.Client{} },
+		readPerm:  models.PermClientsRead,
+		writePerm: models.PermClientsWrite,
+	},
+}
+
+// TrashController lists, restores and purges soft deleted records. Records
+// can only be purged once they have been in the trash for the retention
+// period of the configuration.
+type TrashController struct {
+	DB     *gorm.DB
+	Config *initializers.Config
+}
+
+func NewTrashController(DB *gorm.DB, Config *initializers.Config) TrashController {
+	return TrashController{DB, Config}
+}
+
+// trashEntityOf returns the trash type of the request when the current user
+// has the permission permission picks from it. It writes the error response
+// itself.
+func trashEntityOf(ctx *gin.Context, permission func(trashEntity) string) (trashEntity, bool) {
+	entity, ok := trashEntities[ctx.Param("type")]
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Unknown trash type"})
+		return entity, false
+	}
+	if !middleware.HasPermission(ctx, permission(entity)) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "You do not have permission to perform this action"})
+		return entity, false
+	}
+	return entity, true
+}
+
+// GetTrash lists the deleted records of a type, the latest deleted first.
+// Paging: page and limit.
+func (tc *TrashController) GetTrash(ctx *gin.Context) {
+	entity, ok := trashEntityOf(ctx, func(e trashEntity) string { return e.readPerm })
+	if !ok {
+		return
+	}
+
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 50, 200)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	base := tc.DB.Unscoped().Model(entity.model()).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve the trash"})
+		return
+	}
+
+	records := entity.list()
+	if err := base.Session(&gorm.Session{}).
+		Order("deleted_at DESC").Limit(page.Limit).Offset(page.Offset).
+		Find(records).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve the trash"})
+		return
+	}
+
+	canReadPricing := middleware.HasPermission(ctx, models.PermPricingRead)
+	canReadSalary := middleware.HasPermission(ctx, models.PermSalaryRead)
+	switch list := records.(type) {
+	case *[]models.Order:
+		for i := range *list {
+			if !canReadPricing {
+				(*list)[i].HidePricing()
+			}
+			if !canReadSalary {
+				(*list)[i].HideSalary()
+			}
+		}
+	case *[]models.Driver:
+		if !canReadSalary {
+			for i := range *list {
+				(*list)[i].HideSalary()
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"total":     total,
+		"page":      page.Page,
+		"limit":     page.Limit,
+		"retention": tc.Config.TrashRetention.String(),
+		"data":      records,
+	})
+}
+
+// RestoreTrash takes a deleted record out of the trash
+func (tc *TrashController) RestoreTrash(ctx *gin.Context) {
+	entity, ok := trashEntityOf(ctx, func(e trashEntity) string { return e.writePerm })
+	if !ok {
+		return
+	}
+
+	id := ctx.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid ID format"})
+		return
+	}
+
+	result := tc.DB.WithContext(ctx).Unscoped().Model(entity.model()).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to restore the record"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No record with that ID is in the trash"})
+		return
+	}
+
+	record := entity.model()
+	if err := tc.DB.First(record, "id = ?", id).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve the restored record"})
+		return
+	}
+	switch restored := record.(type) {
+	case *models.Order:
+		if !middleware.HasPermission(ctx, models.PermPricingRead) {
+			restored.HidePricing()
+		}
+		if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+			restored.HideSalary()
+		}
+	case *models.Driver:
+		if !middleware.HasPermission(ctx, models.PermSalaryRead) {
+			restored.HideSalary()
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": record})
+}
+
+// PurgeTrash permanently deletes a record that has been in the trash for
+// longer than the retention period
+func (tc *TrashController) PurgeTrash(ctx *gin.Context) {
+	entity, ok := trashEntityOf(ctx, func(trashEntity) string { return models.PermTrashPurge })
+	if !ok {
+		return
+	}
+
+	id := ctx.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid ID format"})
+		return
+	}
+
+	var deletedAt gorm.DeletedAt
+	if err := tc.DB.Unscoped().Model(entity.model()).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Select("deleted_at").Row().Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No record with that ID is in the trash"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve the record"})
+		}
+		return
+	}
+
+	purgeAfter := deletedAt.Time.Add(tc.Config.TrashRetention)
+	if time.Now().Before(purgeAfter) {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "The record can be purged from " + purgeAfter.Format(time.RFC3339)})
+		return
+	}
+
+	if err := tc.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if entity.purge != nil {
+			if err := entity.purge(tx, id); err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(entity.model()).Error
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to purge the record"})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
@@ -54,7 +54,7 @@ func (tc *TruckController) CreateTruck(ctx *gin.Context) {
 	}
 
 	// Insert the new truck into the database
-	result := tc.DB.Create(&newTruck)
+	result := tc.DB.WithContext(ctx).Create(&newTruck)
 	if result.Error != nil {
 		if strings.Contains(result.Error.Error(), "duplicate key") {
 			ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "Truck with that license plate already exists"})
@@ -122,7 +122,7 @@ func (tc *TruckController) UpdateTruck(ctx *gin.Context) {
 	}
 
 	// Apply updates
-	tc.DB.Model(&truck).Updates(updates)
+	tc.DB.WithContext(ctx).Model(&truck).Updates(updates)
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": truck})
 }
@@ -174,7 +174,7 @@ func (tc *TruckController) DeleteTruck(ctx *gin.Context) {
 	}
 
 	// Perform Soft Delete
-	if err := tc.DB.Delete(&truck).Error; err != nil {
+	if err := tc.DB.WithContext(ctx).Delete(&truck).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
@@ -209,7 +209,7 @@ func (tc *TruckController) DeleteTrucks(ctx *gin.Context) {
 		}
 
 		// Try to delete the truck
-		result := tc.DB.Where("id = ?", id).Delete(&models.Truck{})
+		result := tc.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.Truck{})
 		if result.Error != nil {
 			failedIDs = append(failedIDs, id)
 			continue // Skip errors and continue with other IDs
@@ -3,10 +3,14 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
 	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
 	"gorm.io/gorm"
 )
 
@@ -95,10 +99,11 @@ func (uc *UserController) UpdateUser(ctx *gin.Context) {
 	// Update allowed fields
 	user.Name = updatedData.Name
 
-	if err := uc.DB.Save(&user).Error; err != nil {
+	if err := uc.DB.WithContext(ctx).Save(&user).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update user"})
 		return
 	}
+	middleware.InvalidateUser(user.ID)
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "User updated successfully", "data": user})
 }
@@ -119,10 +124,368 @@ func (uc *UserController) DeleteUser(ctx *gin.Context) {
 	}
 
 	// Soft delete user
-	if err := uc.DB.Delete(&user).Error; err != nil {
+	if err := uc.DB.WithContext(ctx).Delete(&user).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to delete user"})
 		return
 	}
+	middleware.InvalidateUser(user.ID)
 
 	ctx.JSON(http.StatusNoContent, gin.H{"status": "success", "message": "User deleted successfully"})
 }
+
+// FindRoles lists every role with the permissions it grants and its security policy
+func (uc *UserController) FindRoles(ctx *gin.Context) {
+	var policies []models.RolePolicy
+	if err := uc.DB.Find(&policies).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve role policies"})
+		return
+	}
+
+	requireTwoFactor := map[string]bool{}
+	for _, policy := range policies {
+		requireTwoFactor[policy.Role] = policy.RequireTwoFactor
+	}
+
+	roles := []models.RoleResponse{}
+	for _, role := range []string{models.RoleAdmin, models.RoleDispatcher, models.RoleAccountant, models.RoleDriver, models.RoleContractor, models.RoleReadOnly} {
+		roles = append(roles, models.RoleResponse{Role: role, Permissions: models.PermissionsForRole(role), RequireTwoFactor: requireTwoFactor[role]})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": roles})
+}
+
+// UpdateRolePolicy changes the security policy of a role, such as requiring two-factor authentication
+func (uc *UserController) UpdateRolePolicy(ctx *gin.Context) {
+	role := ctx.Param("role")
+	var payload models.UpdateRolePolicyInput
+
+	if !models.IsValidRole(role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid role"})
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	policy := models.RolePolicy{
+		Role:             role,
+		RequireTwoFactor: *payload.RequireTwoFactor,
+		UpdatedAt:        time.Now(),
+	}
+
+	if err := uc.DB.WithContext(ctx).Save(&policy).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update role policy"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": policy})
+}
+
+// UpdateUserRole assigns a role to a user
+func (uc *UserController) UpdateUserRole(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	userID := ctx.Param("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+		return
+	}
+
+	var payload models.UpdateRoleInput
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if !models.IsValidRole(payload.Role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid role"})
+		return
+	}
+
+	// Prevent admins from locking themselves out
+	if currentUser.ID.String() == userID && payload.Role != models.RoleAdmin {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "You cannot change your own role"})
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, "id = ?", userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+		return
+	}
+
+	if err := uc.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{"role": payload.Role, "updated_at": time.Now()}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update user role"})
+		return
+	}
+	middleware.InvalidateUser(user.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": user})
+}
+
+// LinkDriver ties a user to the driver record they can see through the
+// driver portal. A null driver_id removes the link.
+func (uc *UserController) LinkDriver(ctx *gin.Context) {
+	userID := ctx.Param("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+		return
+	}
+
+	var payload models.LinkDriverInput
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, "id = ?", userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+		return
+	}
+
+	if payload.DriverID != nil {
+		var driver models.Driver
+		if err := uc.DB.First(&driver, "id = ?", *payload.DriverID).Error; err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Driver not found"})
+			return
+		}
+	}
+
+	err := uc.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{"driver_id": payload.DriverID, "updated_at": time.Now()}).Error
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique") {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "That driver is already linked to another user"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to link driver"})
+		return
+	}
+	middleware.InvalidateUser(user.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": user})
+}
+
+// LinkContractor ties a partner account to the external contractor whose
+// data it can see through the contractor portal. A null contractor_id
+// removes the link.
+func (uc *UserController) LinkContractor(ctx *gin.Context) {
+	userID := ctx.Param("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+		return
+	}
+
+	var payload models.LinkContractorInput
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, "id = ?", userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+		return
+	}
+
+	if payload.ContractorID != nil {
+		var contractor models.Contractor
+		if err := uc.DB.First(&contractor, "id = ?", *payload.ContractorID).Error; err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "Contractor not found"})
+			return
+		}
+		if contractor.Type != models.ContractorTypeExternal {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Only external contractors can have partner accounts"})
+			return
+		}
+	}
+
+	if err := uc.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{"contractor_id": payload.ContractorID, "updated_at": time.Now()}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to link contractor"})
+		return
+	}
+	middleware.InvalidateUser(user.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": user})
+}
+
+// RevokeUserSessions logs a user out of every device
+func (uc *UserController) RevokeUserSessions(ctx *gin.Context) {
+	userID := ctx.Param("userId")
+	var user models.User
+
+	if _, err := uuid.Parse(userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+		return
+	}
+
+	if err := uc.DB.First(&user, "id = ?", userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+		return
+	}
+
+	if err := revokeUserSessions(uc.DB, user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to revoke sessions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "All sessions revoked"})
+}
+
+// ResetUserPassword lets an admin set a new password for a user, which also
+// lifts a lockout
+func (uc *UserController) ResetUserPassword(ctx *gin.Context) {
+	userID := ctx.Param("userId")
+	var payload *models.AdminResetPasswordInput
+	var user models.User
+
+	if _, err := uuid.Parse(userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if payload.Password != payload.PasswordConfirm {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Passwords do not match"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(payload.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	if err := uc.DB.First(&user, "id = ?", userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+		return
+	}
+
+	if err := uc.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{
+		"password":             hashedPassword,
+		"failed_login_count":   0,
+		"locked_until":         nil,
+		"last_failed_login_at": nil,
+		"updated_at":           time.Now(),
+	}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update password"})
+		return
+	}
+
+	// A new password must log the user out of every device
+	if err := revokeUserSessions(uc.DB, user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to revoke sessions"})
+		return
+	}
+
+	userResponse := &models.UserResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Photo:     user.Photo,
+		Role:      user.Role,
+		Provider:  user.Provider,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"user": userResponse}})
+}
+
+// UnlockUser clears a lockout caused by failed login attempts
+func (uc *UserController) UnlockUser(ctx *gin.Context) {
+	userID := ctx.Param("userId")
+	var user models.User
+
+	if _, err := uuid.Parse(userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+		return
+	}
+
+	if err := uc.DB.First(&user, "id = ?", userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+		return
+	}
+
+	if err := uc.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{"failed_login_count": 0, "locked_until": nil, "last_failed_login_at": nil}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to unlock user"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "User unlocked successfully"})
+}
+
+// FindLoginHistory lists login attempts, newest first. It can be narrowed
+// to one user with the userId path parameter or the user_id query, and to
+// one client with the ip query.
+func (uc *UserController) FindLoginHistory(ctx *gin.Context) {
+	page, err := utils.ParsePage(ctx.Query("page"), ctx.Query("limit"), 200, 1000)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	userID := ctx.Param("userId")
+	if userID == "" {
+		userID = ctx.Query("user_id")
+	}
+
+	query := uc.DB.Model(&models.LoginAttempt{})
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if ip := ctx.Query("ip"); ip != "" {
+		query = query.Where("client_ip = ?", ip)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var attempts []models.LoginAttempt
+	if err := query.Order("created_at DESC").Limit(page.Limit).Offset(page.Offset).Find(&attempts).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve login history"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(attempts), "total": total, "data": attempts})
+}
+
+// ResetUserTwoFactor removes the second factor of a user who lost their device.
+// Users whose role requires it will be asked to enroll again on their next login.
+func (uc *UserController) ResetUserTwoFactor(ctx *gin.Context) {
+	userID := ctx.Param("userId")
+	var user models.User
+
+	if _, err := uuid.Parse(userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid user ID format"})
+		return
+	}
+
+	if err := uc.DB.First(&user, "id = ?", userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User not found"})
+		return
+	}
+
+	if err := clearTwoFactor(uc.DB.WithContext(ctx), user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to reset two-factor authentication"})
+		return
+	}
+
+	if err := revokeUserSessions(uc.DB, user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to revoke sessions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Two-factor authentication reset"})
+}
@@ -0,0 +1,328 @@
+package initializers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// auditedTables are the tables whose changes are written to the audit log
+var auditedTables = map[string]bool{
+	"orders":          true,
+	"order_stops":     true,
+	"order_pods":      true,
+	"order_templates": true,
+	"holidays":        true,
+	"payslips":        true,
+	"pricings":        true,
+	"price_details":   true,
+	"trucks":          true,
+	"drivers":         true,
+	"contractors":     true,
+	"clients":         true,
+	"settings":        true,
+	"settlements":     true,
+	"users":           true,
+}
+
+// auditIgnoredColumns change on every write or every login and would only
+// add noise to the diff. Logins are recorded in the login history instead.
+var auditIgnoredColumns = map[string]bool{
+	"updated_at":           true,
+	"version":              true,
+	"failed_login_count":   true,
+	"last_failed_login_at": true,
+	"totp_last_used_step":  true,
+}
+
+const auditBeforeKey = "audit:before"
+
+// RegisterAuditCallbacks writes an audit log entry for every row created,
+// updated or deleted in an audited table, in the same transaction as the
+// change. The acting user is read from the statement context, so requests
+// must run their writes with DB.WithContext(ctx).
+func RegisterAuditCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_create", auditAfterCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").After("gorm:setup_reflect_value").
+		Register("audit:before_update", auditBeforeChange); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_update", auditAfterUpdate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").After("gorm:begin_transaction").
+		Register("audit:before_delete", auditBeforeChange); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_delete", auditAfterDelete)
+}
+
+func audited(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil && auditedTables[db.Statement.Schema.Table]
+}
+
+func auditAfterCreate(db *gorm.DB) {
+	if !audited(db) || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	var logs []models.AuditLog
+	for _, row := range reflectRows(db.Statement.ReflectValue) {
+		logs = append(logs, newAuditLog(db, models.AuditActionCreate, nil, auditRow(db, row)))
+	}
+	writeAuditLogs(db, logs)
+}
+
+// auditBeforeChange loads the rows an update or delete is about to change
+func auditBeforeChange(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+
+	query, ok := auditTargetQuery(db)
+	if !ok {
+		return
+	}
+
+	rows, err := loadAuditRows(db, query)
+	if err != nil {
+		db.AddError(fmt.Errorf("could not load rows for the audit log: %w", err))
+		return
+	}
+	db.Statement.Settings.Store(auditBeforeKey, rows)
+}
+
+func auditAfterUpdate(db *gorm.DB) {
+	before := auditBeforeRows(db)
+	if !audited(db) || len(before) == 0 || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	primaryKey := db.Statement.Schema.PrioritizedPrimaryField
+	ids := make([]interface{}, 0, len(before))
+	for _, row := range before {
+		ids = append(ids, row[primaryKey.DBName])
+	}
+
+	after, err := loadAuditRows(db, auditQuery(db).Unscoped().Where(clause.IN{Column: clause.PrimaryColumn, Values: ids}))
+	if err != nil {
+		db.AddError(fmt.Errorf("could not load rows for the audit log: %w", err))
+		return
+	}
+
+	afterByID := make(map[string]models.JSONBObject, len(after))
+	for _, row := range after {
+		afterByID[fmt.Sprint(row[primaryKey.DBName])] = row
+	}
+
+	var logs []models.AuditLog
+	for _, row := range before {
+		changedBefore, changedAfter := diffAuditRows(row, afterByID[fmt.Sprint(row[primaryKey.DBName])])
+		if len(changedAfter) == 0 {
+			continue
+		}
+
+		log := newAuditLog(db, models.AuditActionUpdate, changedBefore, changedAfter)
+		log.EntityID = fmt.Sprint(row[primaryKey.DBName])
+		logs = append(logs, log)
+	}
+	writeAuditLogs(db, logs)
+}
+
+func auditAfterDelete(db *gorm.DB) {
+	before := auditBeforeRows(db)
+	if !audited(db) || len(before) == 0 || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	logs := make([]models.AuditLog, 0, len(before))
+	for _, row := range before {
+		logs = append(logs, newAuditLog(db, models.AuditActionDelete, row, nil))
+	}
+	writeAuditLogs(db, logs)
+}
+
+// auditQuery starts a query on the audited table that runs in the same
+// transaction as the statement and does not trigger the callbacks again
+func auditQuery(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Model(reflect.New(db.Statement.Schema.ModelType).Interface())
+}
+
+// auditTargetQuery selects the rows the statement will change, using its
+// conditions and the primary keys of the values it was given. It refuses to
+// build a query without conditions, GORM rejects those statements anyway.
+func auditTargetQuery(db *gorm.DB) (*gorm.DB, bool) {
+	stmt := db.Statement
+	query := auditQuery(db)
+	if stmt.Unscoped {
+		query = query.Unscoped()
+	}
+
+	hasConditions := false
+	if where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+		query = query.Clauses(where)
+		hasConditions = true
+	}
+
+	if primaryKey := stmt.Schema.PrioritizedPrimaryField; primaryKey != nil {
+		var ids []interface{}
+		for _, row := range reflectRows(stmt.ReflectValue) {
+			if value, zero := primaryKey.ValueOf(stmt.Context, row); !zero {
+				ids = append(ids, value)
+			}
+		}
+		if len(ids) > 0 {
+			query = query.Where(clause.IN{Column: clause.PrimaryColumn, Values: ids})
+			hasConditions = true
+		}
+	}
+
+	return query, hasConditions || db.AllowGlobalUpdate
+}
+
+func loadAuditRows(db *gorm.DB, query *gorm.DB) ([]models.JSONBObject, error) {
+	results := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	if err := query.Find(results.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make([]models.JSONBObject, 0, results.Elem().Len())
+	for _, row := range reflectRows(results) {
+		rows = append(rows, auditRow(db, row))
+	}
+	return rows, nil
+}
+
+func auditBeforeRows(db *gorm.DB) []models.JSONBObject {
+	value, ok := db.Statement.Settings.Load(auditBeforeKey)
+	if !ok {
+		return nil
+	}
+	return value.([]models.JSONBObject)
+}
+
+// auditRow returns the columns of a row. Fields hidden from the API with
+// json:"-" (passwords, secrets) are replaced by a fingerprint, so the log
+// shows that they changed without storing them.
+func auditRow(db *gorm.DB, row reflect.Value) models.JSONBObject {
+	data := models.JSONBObject{}
+	for _, field := range db.Statement.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		value, _ := field.ValueOf(db.Statement.Context, row)
+		if isHiddenField(field) {
+			encoded, _ := json.Marshal(value)
+			sum := sha256.Sum256(encoded)
+			value = "redacted:" + hex.EncodeToString(sum[:6])
+		}
+		data[field.DBName] = value
+	}
+	return data
+}
+
+func isHiddenField(field *schema.Field) bool {
+	return strings.Split(field.Tag.Get("json"), ",")[0] == "-"
+}
+
+// diffAuditRows keeps only the columns whose value changed
+func diffAuditRows(before models.JSONBObject, after models.JSONBObject) (models.JSONBObject, models.JSONBObject) {
+	changedBefore, changedAfter := models.JSONBObject{}, models.JSONBObject{}
+	for column, value := range after {
+		if auditIgnoredColumns[column] {
+			continue
+		}
+
+		oldJSON, _ := json.Marshal(before[column])
+		newJSON, _ := json.Marshal(value)
+		if !bytes.Equal(oldJSON, newJSON) {
+			changedBefore[column] = before[column]
+			changedAfter[column] = value
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+func newAuditLog(db *gorm.DB, action string, before models.JSONBObject, after models.JSONBObject) models.AuditLog {
+	log := models.AuditLog{
+		ID:         uuid.New(),
+		EntityType: db.Statement.Schema.Table,
+		Action:     action,
+		Before:     before,
+		After:      after,
+		CreatedAt:  time.Now(),
+	}
+
+	row := after
+	if row == nil {
+		row = before
+	}
+	if primaryKey := db.Statement.Schema.PrioritizedPrimaryField; primaryKey != nil && row != nil {
+		log.EntityID = fmt.Sprint(row[primaryKey.DBName])
+	}
+
+	setAuditActor(db.Statement.Context, &log)
+	return log
+}
+
+// setAuditActor reads the user that DeserializeUser put on the request
+func setAuditActor(ctx context.Context, log *models.AuditLog) {
+	if ctx == nil {
+		return
+	}
+	if user, ok := ctx.Value("currentUser").(models.User); ok {
+		log.ActorID = &user.ID
+		log.ActorEmail = user.Email
+	}
+	if apiKey, ok := ctx.Value("currentAPIKey").(models.APIKey); ok {
+		log.APIKeyID = &apiKey.ID
+	}
+	if ginCtx, ok := ctx.(*gin.Context); ok && ginCtx.Request != nil {
+		log.ClientIP = ginCtx.ClientIP()
+	}
+}
+
+func writeAuditLogs(db *gorm.DB, logs []models.AuditLog) {
+	if len(logs) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&logs).Error; err != nil {
+		db.AddError(fmt.Errorf("could not write audit log: %w", err))
+	}
+}
+
+func reflectRows(value reflect.Value) []reflect.Value {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		rows := make([]reflect.Value, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			rows = append(rows, reflect.Indirect(value.Index(i)))
+		}
+		return rows
+	case reflect.Struct:
+		return []reflect.Value{value}
+	}
+	return nil
+}
@@ -18,5 +18,9 @@ func ConnectDB(config *Config) {
 	if err != nil {
 		log.Fatal("Failed to connect to the Database")
 	}
+
+	if err := RegisterAuditCallbacks(DB); err != nil {
+		log.Fatal("Failed to register the audit log callbacks: ", err)
+	}
 	fmt.Println("🚀 Connected Successfully to the Database")
 }
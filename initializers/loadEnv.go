@@ -26,7 +26,33 @@ type Config struct {
 	AccessTokenMaxAge      int           `mapstructure:"ACCESS_TOKEN_MAXAGE"`
 	RefreshTokenMaxAge     int           `mapstructure:"REFRESH_TOKEN_MAXAGE"`
 
+	// How often the server picks up signing keys added or retired with the keys command
+	SigningKeyReloadInterval time.Duration `mapstructure:"SIGNING_KEY_RELOAD_INTERVAL"`
+
 	UploadFilePath string `mapstructure:"UPLOAD_FILE_PATH"`
+
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     string `mapstructure:"SMTP_PORT"`
+	SMTPUser     string `mapstructure:"SMTP_USER"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	EmailFrom    string `mapstructure:"EMAIL_FROM"`
+
+	PasswordResetTokenExpiresIn     time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_EXPIRED_IN"`
+	EmailVerificationTokenExpiresIn time.Duration `mapstructure:"EMAIL_VERIFICATION_TOKEN_EXPIRED_IN"`
+	InvitationExpiresIn             time.Duration `mapstructure:"INVITATION_EXPIRED_IN"`
+
+	// How long deleted records stay in the trash before they can be purged
+	TrashRetention time.Duration `mapstructure:"TRASH_RETENTION"`
+
+	// How often draft orders are booked from the order templates, 0 turns
+	// the scheduler off. Every server may run it, a template day is only
+	// booked once.
+	OrderTemplateInterval time.Duration `mapstructure:"ORDER_TEMPLATE_INTERVAL"`
+
+	TOTPIssuer string `mapstructure:"TOTP_ISSUER"`
+
+	// Open registration is off unless explicitly enabled, users join by invitation
+	AllowRegistration bool `mapstructure:"ALLOW_REGISTRATION"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -47,6 +73,16 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigType("env")
 	viper.SetConfigName(configFile)
 
+	// Defaults for optional settings
+	viper.SetDefault("PASSWORD_RESET_TOKEN_EXPIRED_IN", "30m")
+	viper.SetDefault("EMAIL_VERIFICATION_TOKEN_EXPIRED_IN", "24h")
+	viper.SetDefault("INVITATION_EXPIRED_IN", "72h")
+	viper.SetDefault("ALLOW_REGISTRATION", false)
+	viper.SetDefault("TOTP_ISSUER", "Van Tai T&T")
+	viper.SetDefault("SIGNING_KEY_RELOAD_INTERVAL", "5m")
+	viper.SetDefault("TRASH_RETENTION", "720h")
+	viper.SetDefault("ORDER_TEMPLATE_INTERVAL", "1h")
+
 	// Automatically read environment variables
 	viper.AutomaticEnv()
 
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/wpcodevo/golang-gorm-postgres/initializers"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
+)
+
+// Manages the keys that sign access and refresh tokens.
+//
+//	go run keys/keys.go list
+//	go run keys/keys.go generate [-use access|refresh] [-bits 2048]
+//	go run keys/keys.go retire <kid>...
+//	go run keys/keys.go retire -older-than 720h
+//
+// A generated key starts signing on every server within
+// SIGNING_KEY_RELOAD_INTERVAL. Tokens signed by older keys stay valid until
+// those keys are retired, so only retire a key once the tokens it signed have
+// expired (REFRESH_TOKEN_EXPIRED_IN for refresh keys).
+
+var config initializers.Config
+
+func init() {
+	var err error
+	config, err = initializers.LoadConfig(".")
+	if err != nil {
+		log.Fatal("🚀 Could not load environment variables", err)
+	}
+
+	initializers.ConnectDB(&config)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	if err := importEnvironmentKeys(); err != nil {
+		log.Fatal("Could not import the environment keys: ", err)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = listKeys()
+	case "generate":
+		err = generateKey(os.Args[2:])
+	case "retire":
+		err = retireKeys(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: keys list | generate [-use access|refresh] [-bits 2048] | retire <kid>... | retire -older-than <duration>")
+	os.Exit(2)
+}
+
+// importEnvironmentKeys records the key pairs from the environment under their
+// legacy key ids, so they can be listed and retired like any other key
+func importEnvironmentKeys() error {
+	envKeys := map[string][2]string{
+		utils.KeyUseAccess:  {config.AccessTokenPrivateKey, config.AccessTokenPublicKey},
+		utils.KeyUseRefresh: {config.RefreshTokenPrivateKey, config.RefreshTokenPublicKey},
+	}
+
+	for use, pair := range envKeys {
+		var count int64
+		initializers.DB.Model(&models.SigningKey{}).Where("id = ?", utils.LegacyKeyID(use)).Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		privateKey, err := base64.StdEncoding.DecodeString(pair[0])
+		if err != nil {
+			return fmt.Errorf("%s token: could not decode key: %w", use, err)
+		}
+		publicKey, err := base64.StdEncoding.DecodeString(pair[1])
+		if err != nil {
+			return fmt.Errorf("%s token: could not decode key: %w", use, err)
+		}
+
+		// Sorts before every generated key, which is what keeps it from signing
+		// once a newer key exists
+		if err := initializers.DB.Create(&models.SigningKey{
+			ID:         utils.LegacyKeyID(use),
+			Use:        use,
+			PrivateKey: string(privateKey),
+			PublicKey:  string(publicKey),
+			CreatedAt:  time.Unix(0, 0),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listKeys() error {
+	var signingKeys []models.SigningKey
+	if err := initializers.DB.Order("use, created_at").Find(&signingKeys).Error; err != nil {
+		return err
+	}
+
+	signers := currentSigners(signingKeys)
+	for _, key := range signingKeys {
+		status := "verifying"
+		if key.RetiredAt != nil {
+			status = "retired " + key.RetiredAt.Format(time.RFC3339)
+		} else if signers[key.Use] == key.ID {
+			status = "signing"
+		}
+		fmt.Printf("%-8s %-28s %-25s %s\n", key.Use, key.ID, key.CreatedAt.Format(time.RFC3339), status)
+	}
+	return nil
+}
+
+func generateKey(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	use := flags.String("use", "", "generate only an access or a refresh key (default both)")
+	bits := flags.Int("bits", 2048, "RSA key size")
+	flags.Parse(args)
+
+	uses := []string{utils.KeyUseAccess, utils.KeyUseRefresh}
+	if *use != "" {
+		if *use != utils.KeyUseAccess && *use != utils.KeyUseRefresh {
+			return fmt.Errorf("unknown key use %q", *use)
+		}
+		uses = []string{*use}
+	}
+
+	for _, use := range uses {
+		privateKey, publicKey, err := utils.GenerateSigningKeyPEM(*bits)
+		if err != nil {
+			return err
+		}
+
+		suffix, err := utils.GenerateRandomToken(6)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		key := models.SigningKey{
+			ID:         fmt.Sprintf("%s-%s-%s", use, now.Format("20060102"), suffix),
+			Use:        use,
+			PrivateKey: privateKey,
+			PublicKey:  publicKey,
+			CreatedAt:  now,
+		}
+		if err := initializers.DB.Create(&key).Error; err != nil {
+			return err
+		}
+		fmt.Printf("👍 Generated %s key %s\n", use, key.ID)
+	}
+	return nil
+}
+
+func retireKeys(args []string) error {
+	flags := flag.NewFlagSet("retire", flag.ExitOnError)
+	olderThan := flags.Duration("older-than", 0, "retire every key created longer ago than this")
+	flags.Parse(args)
+
+	if *olderThan == 0 && flags.NArg() == 0 {
+		usage()
+	}
+
+	var signingKeys []models.SigningKey
+	if err := initializers.DB.Where("retired_at IS NULL").Order("created_at").Find(&signingKeys).Error; err != nil {
+		return err
+	}
+	signers := currentSigners(signingKeys)
+
+	requested := make(map[string]bool)
+	for _, id := range flags.Args() {
+		requested[id] = true
+	}
+
+	now := time.Now()
+	retired := 0
+	for _, key := range signingKeys {
+		if !requested[key.ID] && (*olderThan == 0 || now.Sub(key.CreatedAt) < *olderThan) {
+			continue
+		}
+		delete(requested, key.ID)
+
+		// Retiring the signer would leave nothing to sign new tokens with
+		if signers[key.Use] == key.ID {
+			fmt.Printf("Skipping %s, it is the current %s signing key. Generate a new key first.\n", key.ID, key.Use)
+			continue
+		}
+
+		if err := initializers.DB.Model(&key).Update("retired_at", now).Error; err != nil {
+			return err
+		}
+		fmt.Printf("👍 Retired %s key %s\n", key.Use, key.ID)
+		retired++
+	}
+
+	for id := range requested {
+		fmt.Printf("No active key %s\n", id)
+	}
+	if retired == 0 {
+		fmt.Println("No keys retired")
+	}
+	return nil
+}
+
+// currentSigners returns the id of the key that signs new tokens for each use
+func currentSigners(signingKeys []models.SigningKey) map[string]string {
+	signers := make(map[string]string)
+	latest := make(map[string]models.SigningKey)
+	for _, key := range signingKeys {
+		if key.RetiredAt != nil || key.PrivateKey == "" {
+			continue
+		}
+		if current, ok := latest[key.Use]; !ok || !key.CreatedAt.Before(current.CreatedAt) {
+			latest[key.Use] = key
+			signers[key.Use] = key.ID
+		}
+	}
+	return signers
+}
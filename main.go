@@ -1,18 +1,27 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/initializers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 	"github.com/wpcodevo/golang-gorm-postgres/routes"
+	"github.com/wpcodevo/golang-gorm-postgres/utils"
 )
 
 var (
 	server              *gin.Engine
+	config              initializers.Config
 	AuthController      controllers.AuthController
 	AuthRouteController routes.AuthRouteController
 
@@ -40,6 +49,12 @@ var (
 	OrderController      controllers.OrderController
 	OrderRouteController routes.OrderRouteController
 
+	OrderPODController      controllers.OrderPODController
+	OrderPODRouteController routes.OrderPODRouteController
+
+	OrderTemplateController      controllers.OrderTemplateController
+	OrderTemplateRouteController routes.OrderTemplateRouteController
+
 	PayslipController      controllers.PayslipController
 	PayslipRouteController routes.PayslipRouteController
 
@@ -48,17 +63,58 @@ var (
 
 	SettingController      controllers.SettingController
 	SettingRouteController routes.SettingRouteController
+
+	InvitationController      controllers.InvitationController
+	InvitationRouteController routes.InvitationRouteController
+
+	TrashController      controllers.TrashController
+	TrashRouteController routes.TrashRouteController
+
+	APIKeyController      controllers.APIKeyController
+	APIKeyRouteController routes.APIKeyRouteController
+
+	AuditLogController      controllers.AuditLogController
+	AuditLogRouteController routes.AuditLogRouteController
+
+	DriverPortalController      controllers.DriverPortalController
+	DriverPortalRouteController routes.DriverPortalRouteController
+
+	ContractorPortalController      controllers.ContractorPortalController
+	ContractorPortalRouteController routes.ContractorPortalRouteController
+
+	SettlementController      controllers.SettlementController
+	SettlementRouteController routes.SettlementRouteController
+
+	JWKSController      controllers.JWKSController
+	JWKSRouteController routes.JWKSRouteController
 )
 
 func init() {
-	config, err := initializers.LoadConfig(".")
+	var err error
+	config, err = initializers.LoadConfig(".")
 	if err != nil {
 		log.Fatal("🚀 Could not load environment variables", err)
 	}
 
 	initializers.ConnectDB(&config)
 
-	AuthController = controllers.NewAuthController(initializers.DB)
+	// Parse the signing keys once, every request reuses them
+	tokens, err := utils.NewTokenManager(config.AccessTokenPrivateKey, config.AccessTokenPublicKey, config.RefreshTokenPrivateKey, config.RefreshTokenPublicKey, loadSigningKeys)
+	if err != nil {
+		log.Fatal("🚀 Could not load token keys", err)
+	}
+	tokens.StartReloading(config.SigningKeyReloadInterval)
+	middleware.Configure(tokens)
+
+	// Without an SMTP host emails are only logged in development. Elsewhere
+	// the flows that send emails are turned off until SMTP_HOST is set.
+	mailer := utils.NewMailer(config.SMTPHost, config.SMTPPort, config.SMTPUser, config.SMTPPassword, config.EmailFrom)
+	if config.SMTPHost == "" && os.Getenv("APP_ENV") != "dev" {
+		log.Println("⚠️  SMTP_HOST is not set: invitations, sign-up verification and password reset emails are disabled. Set SMTP_HOST in app.prod.env to enable them.")
+		mailer = &utils.DisabledMailer{}
+	}
+
+	AuthController = controllers.NewAuthController(initializers.DB, mailer, &config, tokens)
 	AuthRouteController = routes.NewAuthRouteController(AuthController)
 
 	UserController = controllers.NewUserController(initializers.DB)
@@ -85,6 +141,12 @@ func init() {
 	OrderController = controllers.NewOrderController(initializers.DB)
 	OrderRouteController = routes.NewOrderRouteController(OrderController)
 
+	OrderPODController = controllers.NewOrderPODController(initializers.DB, FileController)
+	OrderPODRouteController = routes.NewOrderPODRouteController(OrderPODController)
+
+	OrderTemplateController = controllers.NewOrderTemplateController(initializers.DB, OrderController)
+	OrderTemplateRouteController = routes.NewOrderTemplateRouteController(OrderTemplateController)
+
 	PayslipController = controllers.NewPayslipController(initializers.DB)
 	PayslipRouteController = routes.NewPayslipRouteController(PayslipController)
 
@@ -94,17 +156,55 @@ func init() {
 	SettingController = controllers.NewSettingController(initializers.DB)
 	SettingRouteController = routes.NewSettingRouteController(SettingController)
 
+	InvitationController = controllers.NewInvitationController(initializers.DB, mailer, &config)
+	InvitationRouteController = routes.NewInvitationRouteController(InvitationController)
+
+	TrashController = controllers.NewTrashController(initializers.DB, &config)
+	TrashRouteController = routes.NewTrashRouteController(TrashController)
+
+	APIKeyController = controllers.NewAPIKeyController(initializers.DB)
+	APIKeyRouteController = routes.NewAPIKeyRouteController(APIKeyController)
+
+	AuditLogController = controllers.NewAuditLogController(initializers.DB)
+	AuditLogRouteController = routes.NewAuditLogRouteController(AuditLogController)
+
+	DriverPortalController = controllers.NewDriverPortalController(initializers.DB)
+	DriverPortalRouteController = routes.NewDriverPortalRouteController(DriverPortalController)
+
+	ContractorPortalController = controllers.NewContractorPortalController(initializers.DB)
+	ContractorPortalRouteController = routes.NewContractorPortalRouteController(ContractorPortalController)
+
+	SettlementController = controllers.NewSettlementController(initializers.DB)
+	SettlementRouteController = routes.NewSettlementRouteController(SettlementController)
+
+	JWKSController = controllers.NewJWKSController(tokens)
+	JWKSRouteController = routes.NewJWKSRouteController(JWKSController)
+
 	// Initialize Gin server
 	server = gin.Default()
 }
 
-func main() {
-	// Load the configuration again for server-related settings
-	config, err := initializers.LoadConfig(".")
-	if err != nil {
-		log.Fatal("🚀 Could not load environment variables", err)
+// loadSigningKeys reads the signing keys managed with the keys command
+func loadSigningKeys() ([]utils.SigningKeyPEM, error) {
+	var signingKeys []models.SigningKey
+	if err := initializers.DB.Order("created_at").Find(&signingKeys).Error; err != nil {
+		return nil, err
 	}
 
+	stored := make([]utils.SigningKeyPEM, 0, len(signingKeys))
+	for _, key := range signingKeys {
+		stored = append(stored, utils.SigningKeyPEM{
+			ID:         key.ID,
+			Use:        key.Use,
+			PrivateKey: key.PrivateKey,
+			PublicKey:  key.PublicKey,
+			Retired:    key.RetiredAt != nil,
+		})
+	}
+	return stored, nil
+}
+
+func main() {
 	// Set up CORS configuration
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{"https://vantaitt.com", "https://app.vantaitt.com", "https://api.vantaitt.com", "http://localhost:5173", config.ClientOrigin}
@@ -112,6 +212,7 @@ func main() {
 	corsConfig.AllowHeaders = []string{
 		"Content-Type",
 		"Authorization",
+		"X-API-Key",
 	}
 	// Use CORS middleware
 	server.Use(cors.New(corsConfig))
@@ -123,6 +224,9 @@ func main() {
 		ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": message})
 	})
 
+	// Public keys for verifying access tokens
+	JWKSRouteController.JWKSRoute(&server.RouterGroup)
+
 	// Register routes for various controllers
 	AuthRouteController.AuthRoute(router)
 	UserRouteController.UserRoute(router)
@@ -144,6 +248,12 @@ func main() {
 	// Register Order routes
 	OrderRouteController.OrderRoute(router)
 
+	// Register proof of delivery routes
+	OrderPODRouteController.OrderPODRoute(router)
+
+	// Register order template and holiday routes
+	OrderTemplateRouteController.OrderTemplateRoute(router)
+
 	// Register Payslip routes
 	PayslipRouteController.PayslipRoute(router)
 
@@ -153,6 +263,46 @@ func main() {
 	// Register Setting routes
 	SettingRouteController.SettingRoute(router)
 
+	// Register Invitation routes
+	InvitationRouteController.InvitationRoute(router)
+
+	// Register trash routes
+	TrashRouteController.TrashRoute(router)
+
+	// Register API key routes
+	APIKeyRouteController.APIKeyRoute(router)
+
+	// Register audit log routes
+	AuditLogRouteController.AuditLogRoute(router)
+
+	// Register driver portal routes
+	DriverPortalRouteController.DriverPortalRoute(router)
+
+	// Register contractor portal routes
+	ContractorPortalRouteController.ContractorPortalRoute(router)
+
+	// Register settlement routes
+	SettlementRouteController.SettlementRoute(router)
+
+	// Background jobs and the server stop on SIGINT or SIGTERM, a second
+	// signal stops the process right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	OrderTemplateController.StartScheduler(ctx, config.OrderTemplateInterval)
+
 	// Start the server
-	log.Fatal(server.Run(":" + config.ServerPort))
+	srv := &http.Server{Addr: ":" + config.ServerPort, Handler: server}
+	go func() {
+		<-ctx.Done()
+		stop()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Println("Failed to shut the server down:", err)
+		}
+	}()
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+// authCacheTTL bounds how long a cached user and session are trusted. Changes
+// made through this process invalidate the cache immediately; the TTL only
+// matters for changes made by another instance or directly in the database.
+const authCacheTTL = time.Minute
+
+type authCacheEntry struct {
+	user      models.User
+	session   models.Session
+	expiresAt time.Time
+}
+
+// authCache keeps the user and session of recently seen access tokens so
+// authenticated requests do not need a database round trip. Entries are keyed
+// by the session family id carried in the token's sid claim.
+type authCache struct {
+	mu      sync.RWMutex
+	entries map[string]authCacheEntry
+}
+
+var cache = &authCache{entries: make(map[string]authCacheEntry)}
+
+func (c *authCache) get(familyID string, now time.Time) (authCacheEntry, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[familyID]
+	c.mu.RUnlock()
+
+	if !ok || now.After(entry.expiresAt) || now.After(entry.session.ExpiresAt) {
+		return authCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *authCache) set(familyID string, user models.User, session models.Session, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries now and then so the map does not grow forever
+	if len(c.entries)%256 == 255 {
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	c.entries[familyID] = authCacheEntry{user: user, session: session, expiresAt: now.Add(authCacheTTL)}
+}
+
+// InvalidateUser drops every cached session of the user. Call it after
+// changing the user's role, password, second factor or after revoking sessions.
+func InvalidateUser(userID uuid.UUID) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for key, entry := range cache.entries {
+		if entry.user.ID == userID {
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// InvalidateSessionFamily drops the cached session of one device
+func InvalidateSessionFamily(familyID uuid.UUID) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.entries, familyID.String())
+}
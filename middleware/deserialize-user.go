@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/initializers"
@@ -11,6 +12,15 @@ import (
 	"github.com/wpcodevo/golang-gorm-postgres/utils"
 )
 
+// tokenManager verifies access tokens with the keys parsed at startup
+var tokenManager *utils.TokenManager
+
+// Configure hands the authentication middleware the token manager built once
+// at startup. It must be called before any route using DeserializeUser is served.
+func Configure(tokens *utils.TokenManager) {
+	tokenManager = tokens
+}
+
 func DeserializeUser() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var access_token string
@@ -19,8 +29,10 @@ func DeserializeUser() gin.HandlerFunc {
 		authorizationHeader := ctx.Request.Header.Get("Authorization")
 		fields := strings.Fields(authorizationHeader)
 
-		if len(fields) != 0 && fields[0] == "Bearer" {
+		if len(fields) > 1 && fields[0] == "Bearer" {
 			access_token = fields[1]
+		} else if apiKey := ctx.Request.Header.Get("X-API-Key"); apiKey != "" {
+			access_token = apiKey
 		} else if err == nil {
 			access_token = cookie
 		}
@@ -30,21 +42,84 @@ func DeserializeUser() gin.HandlerFunc {
 			return
 		}
 
-		config, _ := initializers.LoadConfig(".")
-		sub, err := utils.ValidateToken(access_token, config.AccessTokenPublicKey)
+		if strings.HasPrefix(access_token, models.APIKeyPrefix) {
+			deserializeAPIKey(ctx, access_token)
+			return
+		}
+
+		claims, err := tokenManager.ValidateAccessToken(access_token)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": err.Error()})
 			return
 		}
+		sub := fmt.Sprint(claims["sub"])
+
+		// The token is only valid while the session it was issued for is active
+		sid, _ := claims["sid"].(string)
+		if sid == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "Your session has ended, please log in again"})
+			return
+		}
+
+		now := time.Now()
+		entry, cached := cache.get(sid, now)
+		if !cached || entry.user.ID.String() != sub {
+			if err := initializers.DB.First(&entry.session, "family_id = ? AND user_id = ? AND revoked_at IS NULL AND rotated_at IS NULL AND expires_at > ?", sid, sub, now).Error; err != nil {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "Your session has ended, please log in again"})
+				return
+			}
+
+			if err := initializers.DB.First(&entry.user, "id = ?", sub).Error; err != nil {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "the user belonging to this token no logger exists"})
+				return
+			}
 
-		var user models.User
-		result := initializers.DB.First(&user, "id = ?", fmt.Sprint(sub))
-		if result.Error != nil {
-			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "the user belonging to this token no logger exists"})
+			cache.set(sid, entry.user, entry.session, now)
+		}
+		user, session := entry.user, entry.session
+
+		// A token issued before a role change must be refreshed so its claims stay truthful
+		if role, ok := claims["role"].(string); ok && role != user.Role {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "Your role has changed, please refresh your token"})
 			return
 		}
 
 		ctx.Set("currentUser", user)
+		ctx.Set("currentSession", session)
+		ctx.Next()
+	}
+}
+
+// deserializeAPIKey authenticates a machine integration by its API key.
+// The owner of the key becomes the current user.
+func deserializeAPIKey(ctx *gin.Context, key string) {
+	now := time.Now()
+
+	var apiKey models.APIKey
+	result := initializers.DB.Preload("User").First(&apiKey, "key_hash = ?", utils.HashToken(key))
+	if result.Error != nil || !apiKey.IsActive(now) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "Invalid or expired API key"})
+		return
+	}
+
+	// Record usage at most once a minute to avoid a write on every request
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > time.Minute {
+		initializers.DB.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("last_used_at", now)
+	}
+
+	ctx.Set("currentUser", apiKey.User)
+	ctx.Set("currentAPIKey", apiKey)
+	ctx.Next()
+}
+
+// RequireSession must run after DeserializeUser. It rejects requests made
+// with an API key on routes that only make sense for a logged in person.
+func RequireSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, exists := ctx.Get("currentSession"); !exists {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "This action requires a logged in session"})
+			return
+		}
 		ctx.Next()
 	}
 }
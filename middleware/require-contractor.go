@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+// RequireContractor must run after DeserializeUser. It only lets partner
+// accounts linked to a contractor through and puts the contractor's id on the
+// context as currentContractorID, which every contractor portal query is
+// scoped to.
+func RequireContractor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, exists := ctx.Get("currentUser")
+		if !exists {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "You are not logged in"})
+			return
+		}
+
+		user := value.(models.User)
+		if user.Role != models.RoleContractor || user.ContractorID == nil {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Your account is not linked to a contractor"})
+			return
+		}
+
+		ctx.Set("currentContractorID", *user.ContractorID)
+		ctx.Next()
+	}
+}
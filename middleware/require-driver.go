@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+// RequireDriver must run after DeserializeUser. It only lets driver accounts
+// linked to a driver record through and puts that record's id on the context
+// as currentDriverID, which every driver portal query is scoped to.
+func RequireDriver() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, exists := ctx.Get("currentUser")
+		if !exists {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "You are not logged in"})
+			return
+		}
+
+		user := value.(models.User)
+		if user.Role != models.RoleDriver || user.DriverID == nil {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Your account is not linked to a driver"})
+			return
+		}
+
+		ctx.Set("currentDriverID", *user.DriverID)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+// RequirePermission must run after DeserializeUser. It rejects the request
+// when the current user's role does not grant the given permission.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, exists := ctx.Get("currentUser"); !exists {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "You are not logged in"})
+			return
+		}
+
+		if !HasPermission(ctx, permission) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "You do not have permission to perform this action"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// HasPermission reports whether the current request may use the permission.
+// Requests made with an API key are also limited to the scopes of the key.
+func HasPermission(ctx *gin.Context, permission string) bool {
+	value, exists := ctx.Get("currentUser")
+	if !exists {
+		return false
+	}
+
+	if !models.HasPermission(value.(models.User).Role, permission) {
+		return false
+	}
+
+	if apiKey, isAPIKey := ctx.Get("currentAPIKey"); isAPIKey {
+		return apiKey.(models.APIKey).Scopes.Contains(permission)
+	}
+	return true
+}
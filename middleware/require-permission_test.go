@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		status int
+	}{
+		{name: "not logged in", status: http.StatusUnauthorized},
+		{name: "role without the permission", user: &models.User{Role: models.RoleReadOnly}, status: http.StatusForbidden},
+		{name: "role with the permission", user: &models.User{Role: models.RoleDispatcher}, status: http.StatusOK},
+		{name: "admin", user: &models.User{Role: models.RoleAdmin}, status: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/orders", func(ctx *gin.Context) {
+				if test.user != nil {
+					ctx.Set("currentUser", *test.user)
+				}
+			}, RequirePermission(models.PermOrdersWrite), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders", nil))
+			if recorder.Code != test.status {
+				t.Errorf("got status %d, want %d", recorder.Code, test.status)
+			}
+		})
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		apiKey     *models.APIKey
+		permission string
+		want       bool
+	}{
+		{name: "not logged in", permission: models.PermOrdersRead, want: false},
+		{name: "role grants it", user: &models.User{Role: models.RoleDispatcher}, permission: models.PermOrdersWrite, want: true},
+		{name: "role does not grant it", user: &models.User{Role: models.RoleDispatcher}, permission: models.PermPayslipsRead, want: false},
+		{
+			name:       "key scope and role grant it",
+			user:       &models.User{Role: models.RoleDispatcher},
+			apiKey:     &models.APIKey{Scopes: models.JSONBStringList{models.PermOrdersRead, models.PermOrdersWrite}},
+			permission: models.PermOrdersWrite,
+			want:       true,
+		},
+		{
+			name:       "key is not scoped for it",
+			user:       &models.User{Role: models.RoleAdmin},
+			apiKey:     &models.APIKey{Scopes: models.JSONBStringList{models.PermOrdersRead}},
+			permission: models.PermOrdersWrite,
+			want:       false,
+		},
+		{
+			name:       "key scope beyond the owner's role",
+			user:       &models.User{Role: models.RoleReadOnly},
+			apiKey:     &models.APIKey{Scopes: models.JSONBStringList{models.PermOrdersWrite}},
+			permission: models.PermOrdersWrite,
+			want:       false,
+		},
+		{
+			name:       "key without scopes",
+			user:       &models.User{Role: models.RoleAdmin},
+			apiKey:     &models.APIKey{},
+			permission: models.PermOrdersRead,
+			want:       false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if test.user != nil {
+				ctx.Set("currentUser", *test.user)
+			}
+			if test.apiKey != nil {
+				ctx.Set("currentAPIKey", *test.apiKey)
+			}
+			if got := HasPermission(ctx, test.permission); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -20,6 +20,50 @@ func init() {
 func main() {
 	initializers.DB.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\"")
 	initializers.DB.AutoMigrate(&models.User{}, &models.Contractor{}, &models.Truck{}, &models.Driver{}, &models.Pricing{},
-		&models.PriceDetail{}, &models.Order{}, &models.Payslip{}, &models.Client{}, &models.Setting{})
+		&models.PriceDetail{}, &models.Order{}, &models.Payslip{}, &models.Client{}, &models.Setting{},
+		&models.Session{}, &models.UserToken{}, &models.LoginAttempt{}, &models.Invitation{}, &models.APIKey{}, &models.RolePolicy{},
+		&models.SigningKey{}, &models.AuditLog{}, &models.Settlement{}, &models.OrderStatusHistory{}, &models.OrderStop{},
+		&models.OrderPOD{}, &models.OrderPODFile{}, &models.OrderAssignmentOverride{},
+		&models.OrderTemplate{}, &models.Holiday{}, &models.OrderTemplateRun{})
+
+	// Orders saved before end times existed keep their driver and truck busy
+	// for the default duration
+	initializers.DB.Exec("UPDATE orders SET end_time = order_time + interval '8 hours' WHERE end_time IS NULL")
+
+	// Orders from before the status lifecycle have no status history and were
+	// all saved as drafts. Orders of a month whose settlement the contractor
+	// confirmed were billed, other past orders were delivered and upcoming
+	// ones are assigned when they have a driver.
+	initializers.DB.Exec(`UPDATE orders o SET status = CASE
+		WHEN EXISTS (SELECT 1 FROM settlements s WHERE s.contractor_id = o.contractor_id AND s.status = ?
+			AND s.year = EXTRACT(YEAR FROM o.order_time) AND s.month = EXTRACT(MONTH FROM o.order_time)) THEN ?
+		WHEN o.order_time < now() THEN ?
+		WHEN o.driver_id IS NOT NULL THEN ?
+		ELSE ? END
+	WHERE NOT EXISTS (SELECT 1 FROM order_status_histories h WHERE h.order_id = o.id)`,
+		models.SettlementConfirmed, models.OrderStatusInvoiced, models.OrderStatusDelivered, models.OrderStatusAssigned, models.OrderStatusDraft)
+	initializers.DB.Exec(`INSERT INTO order_status_histories (id, order_id, from_status, to_status, changed_by, note, created_at)
+	SELECT uuid_generate_v4(), o.id, '', o.status, 'migration', 'Status derived from the order when statuses were introduced', now()
+	FROM orders o WHERE NOT EXISTS (SELECT 1 FROM order_status_histories h WHERE h.order_id = o.id)`)
+
+	// Weights used to be read in the billing unit, keep the orders that were
+	// billed by weight checkable
+	initializers.DB.Exec("UPDATE orders SET weight_unit = 'kg' WHERE weight_unit = '' AND lower(trim(unit)) = 'kg'")
+	initializers.DB.Exec("UPDATE orders SET weight_unit = 't' WHERE weight_unit = '' AND lower(trim(unit)) IN ('t', 'tấn', 'ton')")
+
+	// Keep the audit log append-only for direct queries as well
+	initializers.DB.Exec(`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql`)
+	initializers.DB.Exec("DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs")
+	initializers.DB.Exec("CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()")
+	initializers.DB.Exec("DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs")
+	initializers.DB.Exec("CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()")
+
+	// Accounts created before roles existed signed themselves up, they can read
+	// until an admin assigns them a role. The first admin is made with the admin command.
+	initializers.DB.Model(&models.User{}).Where("role = ?", "user").Update("role", models.RoleReadOnly)
 	fmt.Println("👍 Migration complete")
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key so they can be told apart from JWTs
+const APIKeyPrefix = "tt_"
+
+// APIKey lets machine integrations call the API with a limited set of scopes.
+// The key acts on behalf of its owner and can never exceed the owner's role.
+// Only the hash of the key is stored.
+type APIKey struct {
+	ID         uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	Name       string          `gorm:"not null" json:"name"`
+	Prefix     string          `gorm:"not null" json:"prefix"`
+	KeyHash    string          `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes     JSONBStringList `gorm:"type:jsonb" json:"scopes"`
+	UserID     uuid.UUID       `gorm:"type:uuid;not null;index" json:"user_id"`
+	User       User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time      `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time      `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time       `gorm:"not null" json:"created_at"`
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog records one change to one row. Entries are written by the audit
+// callbacks registered on the database and are never updated or deleted.
+// Before and After hold the full row for creates and deletes and only the
+// changed columns for updates.
+type AuditLog struct {
+	ID         uuid.UUID   `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	ActorID    *uuid.UUID  `gorm:"type:uuid;index" json:"actor_id,omitempty"`
+	ActorEmail string      `json:"actor_email,omitempty"`
+	APIKeyID   *uuid.UUID  `gorm:"type:uuid" json:"api_key_id,omitempty"`
+	ClientIP   string      `json:"client_ip,omitempty"`
+	EntityType string      `gorm:"type:varchar(64);not null;index:idx_audit_logs_entity" json:"entity_type"`
+	EntityID   string      `gorm:"type:varchar(64);not null;index:idx_audit_logs_entity" json:"entity_id"`
+	Action     string      `gorm:"type:varchar(16);not null" json:"action"`
+	Before     JSONBObject `gorm:"type:jsonb" json:"before"`
+	After      JSONBObject `gorm:"type:jsonb" json:"after"`
+	CreatedAt  time.Time   `gorm:"not null;index" json:"created_at"`
+}
@@ -7,6 +7,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// Contractor types. Only external contractors get partner portal accounts.
+const (
+	ContractorTypeInternal = "internal"
+	ContractorTypeExternal = "external"
+)
+
 type Contractor struct {
 	ID        uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
 	Name      string         `gorm:"not null" json:"name,omitempty"`
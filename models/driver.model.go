@@ -28,6 +28,12 @@ type Driver struct {
 	Contractor Contractor `gorm:"foreignKey:ContractorID" json:"-"`
 }
 
+// HideSalary clears the fixed salary so it is not exposed to roles without
+// access to payroll data
+func (d *Driver) HideSalary() {
+	d.FixedSalary = nil
+}
+
 type CreateDriverRequest struct {
 	FullName      string    `json:"full_name" binding:"required"`
 	Phone         string    `json:"phone"`
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invitation lets an admin onboard a user with a pre-assigned role.
+// Only the hash of the invitation token is stored.
+type Invitation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	Email       string     `gorm:"not null;index" json:"email"`
+	Role        string     `gorm:"type:varchar(255);not null" json:"role"`
+	TokenHash   string     `gorm:"not null;uniqueIndex" json:"-"`
+	InvitedByID uuid.UUID  `gorm:"type:uuid;not null" json:"invited_by_id"`
+	InvitedBy   User       `gorm:"foreignKey:InvitedByID" json:"invited_by,omitempty"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+}
+
+type CreateInvitationInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type AcceptInvitationInput struct {
+	Token           string `json:"token" binding:"required"`
+	Name            string `json:"name" binding:"required"`
+	Password        string `json:"password" binding:"required,min=8"`
+	PasswordConfirm string `json:"password_confirm" binding:"required"`
+	Photo           string `json:"photo,omitempty"`
+}
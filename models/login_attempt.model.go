@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginAttempt is one entry of the login history
+type LoginAttempt struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	Email     string     `gorm:"not null;index" json:"email"`
+	ClientIP  string     `gorm:"not null;index" json:"client_ip"`
+	UserAgent string     `json:"user_agent"`
+	Success   bool       `gorm:"not null" json:"success"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `gorm:"not null;index" json:"created_at"`
+}
@@ -1,50 +1,295 @@
 package models
 
 import (
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Order types. Internal orders are run by our own drivers, external orders
+// are handed to a contractor who is paid PriceForContractor for them.
+const (
+	OrderTypeInternal = "internal"
+	OrderTypeExternal = "external"
 )
 
 type Order struct {
-	ID                   uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
-	ContractorID         uuid.UUID  `gorm:"type:uuid;not null" json:"contractor_id"`
-	Contractor           Contractor `gorm:"foreignKey:ContractorID;references:ID" json:"contractor"`
-	OrderType            string     `gorm:"size:20;not null,default:internal" json:"order_type,omitempty"`
-	OrderTime            time.Time  `json:"order_time"`
-	ClientID             uuid.UUID  `gorm:"type:uuid;" json:"client_id,omitempty"`
-	Client               Client     `gorm:"foreignKey:ClientID" json:"client,omitempty"`
-	DriverID             *uuid.UUID `gorm:"type:uuid" json:"driver_id,omitempty"`
-	Driver               Driver     `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
-	TruckID              *uuid.UUID `gorm:"type:uuid;" json:"truck_id,omitempty"`
-	Truck                Truck      `gorm:"foreignKey:TruckID" json:"truck,omitempty"`
-	PickupProvince       string     `gorm:"size:50;not null" json:"pickup_province"`
-	PickupDistrict       string     `gorm:"size:50" json:"pickup_district"`
-	DeliveryProvince     string     `gorm:"size:50;not null" json:"delivery_province"`
-	DeliveryDistrict     string     `gorm:"size:50" json:"delivery_district"`
-	Unit                 string     `gorm:"size:20;not null" json:"unit"`
-	PackageWeight        *float64   `json:"package_weight"`
-	PackageVolume        *float64   `json:"package_volumn"`
-	TripCount            int        `gorm:"not null;default:1" json:"trip_count"`
-	TripSalary           *float64   `gorm:"not null;default:0" json:"trip_salary"`
-	PriceFromClient      *float64   `gorm:"not null;default:0" json:"price_from_client"`
-	PriceForContractor   *float64   `gorm:"not null;default:0" json:"price_for_contractor"`
-	PriceFromClientID    *uuid.UUID `gorm:"not null;default:uuid_generate_v4()" json:"price_from_client_id"`
-	PriceForContractorID *uuid.UUID `gorm:"not null;default:uuid_generate_v4()" json:"price_for_contractor_id"`
-	DailySalary          *float64   `gorm:"not null;default:0" json:"daily_salary"`
-	PointCount           *int       `gorm:"not null;default:1" json:"point_count"`
-	PointSalary          *float64   `gorm:"not null;default:0" json:"point_salary"`
-	RefundFee            *float64   `gorm:"not null;default:0" json:"recovery_fee"`
-	LoadingSalary        *float64   `gorm:"not null;default:0" json:"loading_salary"`
-	MealFee              *float64   `gorm:"not null;default:0" json:"meal_fee"`
-	StandbyFee           *float64   `gorm:"not null;default:0" json:"standby_fee"`
-	ParkingFee           *float64   `gorm:"not null;default:0" json:"parking_fee"`
-	OtherSalary          *float64   `gorm:"not null;default:0" json:"other_salary"`
-	OutsiteOilFee        *float64   `gorm:"not null;default:0" json:"outside_oil_fee"`
-	OilFee               *float64   `gorm:"not null;default:0" json:"oil_fee"`
-	ChargeFee            *float64   `gorm:"not null;default:0" json:"charge_fee"`
-	TotalSalary          *float64   `gorm:"not null;default:0" json:"total_salary"`
-	Notes                string     `gorm:"type:text" json:"notes"`
-	CreatedAt            time.Time  `json:"created_at"`
-	UpdatedAt            time.Time  `json:"updated_at"`
+	ID                   uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	ContractorID         uuid.UUID      `gorm:"type:uuid;not null" json:"contractor_id"`
+	Contractor           Contractor     `gorm:"foreignKey:ContractorID;references:ID" json:"contractor"`
+	OrderType            string         `gorm:"size:20;not null;default:internal" json:"order_type,omitempty"`
+	Status               string         `gorm:"type:varchar(20);not null;default:draft;index" json:"status"`
+	OrderTime            time.Time      `json:"order_time"`
+	EndTime              *time.Time     `gorm:"index" json:"end_time"` // End of the window the driver and truck are busy, see ApplyEndTime
+	ClientID             uuid.UUID      `gorm:"type:uuid;" json:"client_id,omitempty"`
+	Client               Client         `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+	DriverID             *uuid.UUID     `gorm:"type:uuid" json:"driver_id,omitempty"`
+	Driver               Driver         `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
+	TruckID              *uuid.UUID     `gorm:"type:uuid;" json:"truck_id,omitempty"`
+	Truck                Truck          `gorm:"foreignKey:TruckID" json:"truck,omitempty"`
+	PickupProvince       string         `gorm:"size:50;not null" json:"pickup_province"`
+	PickupDistrict       string         `gorm:"size:50" json:"pickup_district"`
+	DeliveryProvince     string         `gorm:"size:50;not null" json:"delivery_province"`
+	DeliveryDistrict     string         `gorm:"size:50" json:"delivery_district"`
+	Stops                []OrderStop    `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"stops,omitempty"`
+	PODs                 []OrderPOD     `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"pods,omitempty"`
+	Unit                 string         `gorm:"size:20;not null" json:"unit"`
+	PackageWeight        *float64       `json:"package_weight"`
+	WeightUnit           string         `gorm:"size:10;not null;default:''" json:"weight_unit"` // kg or t, see WeightInTons
+	PackageVolume        *float64       `json:"package_volumn"`
+	TripCount            int            `gorm:"not null;default:1" json:"trip_count"`
+	TripSalary           *float64       `gorm:"not null;default:0" json:"trip_salary"`
+	PriceFromClient      *float64       `gorm:"not null;default:0" json:"price_from_client"`
+	PriceForContractor   *float64       `gorm:"not null;default:0" json:"price_for_contractor"`
+	PriceFromClientID    *uuid.UUID     `gorm:"not null;default:uuid_generate_v4()" json:"price_from_client_id"`
+	PriceForContractorID *uuid.UUID     `gorm:"not null;default:uuid_generate_v4()" json:"price_for_contractor_id"`
+	DailySalary          *float64       `gorm:"not null;default:0" json:"daily_salary"`
+	PointCount           *int           `gorm:"not null;default:1" json:"point_count"`
+	PointSalary          *float64       `gorm:"not null;default:0" json:"point_salary"`
+	StopPointSalary      *float64       `json:"stop_point_salary"` // Pay for a drop without its own rate, see ApplyStops
+	RefundFee            *float64       `gorm:"not null;default:0" json:"recovery_fee"`
+	LoadingSalary        *float64       `gorm:"not null;default:0" json:"loading_salary"`
+	MealFee              *float64       `gorm:"not null;default:0" json:"meal_fee"`
+	StandbyFee           *float64       `gorm:"not null;default:0" json:"standby_fee"`
+	ParkingFee           *float64       `gorm:"not null;default:0" json:"parking_fee"`
+	OtherSalary          *float64       `gorm:"not null;default:0" json:"other_salary"`
+	OutsiteOilFee        *float64       `gorm:"not null;default:0" json:"outside_oil_fee"`
+	OilFee               *float64       `gorm:"not null;default:0" json:"oil_fee"`
+	ChargeFee            *float64       `gorm:"not null;default:0" json:"charge_fee"`
+	TotalSalary          *float64       `gorm:"not null;default:0" json:"total_salary"`
+	Notes                string         `gorm:"type:text" json:"notes"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	Version              int            `gorm:"not null;default:1" json:"version"` // Raised on every update, see utils.ExpectedVersion
+	DeletedAt            gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// HideClientPricing clears the client price so contractors only see what
+// they are paid
+func (o *Order) HideClientPricing() {
+	o.PriceFromClient = nil
+	o.PriceFromClientID = nil
+}
+
+// HidePricing clears the client and contractor prices so they are not
+// exposed to roles without access to pricing data
+func (o *Order) HidePricing() {
+	o.PriceFromClient = nil
+	o.PriceForContractor = nil
+	o.PriceFromClientID = nil
+	o.PriceForContractorID = nil
+}
+
+// HideSalary clears the driver pay and fees of the order, its total and the
+// fixed salary of its driver, so they are not exposed to roles without
+// access to payroll data
+func (o *Order) HideSalary() {
+	o.KeepSalary(&Order{})
+	o.Driver.HideSalary()
+}
+
+// KeepSalary puts back the driver pay and fees of previous, so they are not
+// changed by roles without access to payroll data. A sent total is dropped,
+// it is derived again. Drops of sent stops lose their own rates and are paid
+// the stored stop_point_salary.
+func (o *Order) KeepSalary(previous *Order) {
+	o.TripSalary = previous.TripSalary
+	o.DailySalary = previous.DailySalary
+	o.PointSalary = previous.PointSalary
+	o.StopPointSalary = previous.StopPointSalary
+	o.RefundFee = previous.RefundFee
+	o.LoadingSalary = previous.LoadingSalary
+	o.MealFee = previous.MealFee
+	o.StandbyFee = previous.StandbyFee
+	o.ParkingFee = previous.ParkingFee
+	o.OtherSalary = previous.OtherSalary
+	o.OutsiteOilFee = previous.OutsiteOilFee
+	o.OilFee = previous.OilFee
+	o.ChargeFee = previous.ChargeFee
+	o.TotalSalary = nil
+	for i := range o.Stops {
+		o.Stops[i].PointSalary = nil
+	}
+}
+
+// PriceMatch reports which price detail an order price was taken from, or
+// why none applied
+type PriceMatch struct {
+	Matched       bool       `json:"matched"`
+	PricingID     *uuid.UUID `json:"pricing_id,omitempty"`
+	PriceDetailID *uuid.UUID `json:"price_detail_id,omitempty"`
+	Tier          string     `json:"tier,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+}
+
+// OrderPricing is the result of looking up an order's prices in the client's
+// and the contractor's pricing tables
+type OrderPricing struct {
+	Client     PriceMatch `json:"client"`
+	Contractor PriceMatch `json:"contractor"`
+}
+
+// FinancialChanged reports whether a change to the order touches its prices
+// or fees, which are locked once the order is invoiced
+func (o *Order) FinancialChanged(previous *Order) bool {
+	if floatValue(o.PriceFromClient) != floatValue(previous.PriceFromClient) ||
+		floatValue(o.PriceForContractor) != floatValue(previous.PriceForContractor) ||
+		uuidValue(o.PriceFromClientID) != uuidValue(previous.PriceFromClientID) ||
+		uuidValue(o.PriceForContractorID) != uuidValue(previous.PriceForContractorID) ||
+		floatValue(o.TotalSalary) != floatValue(previous.TotalSalary) ||
+		o.TripCount != previous.TripCount ||
+		(o.PointCount == nil) != (previous.PointCount == nil) ||
+		(o.PointCount != nil && *o.PointCount != *previous.PointCount) ||
+		o.OrderType != previous.OrderType {
+		return true
+	}
+
+	current, old := o.salaryComponents(), previous.salaryComponents()
+	for i := range current {
+		if current[i].value != old[i].value {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteChanged reports whether a change to the order affects which price
+// details apply to it
+func (o *Order) RouteChanged(previous *Order) bool {
+	return o.ClientID != previous.ClientID ||
+		o.ContractorID != previous.ContractorID ||
+		o.PickupProvince != previous.PickupProvince ||
+		o.PickupDistrict != previous.PickupDistrict ||
+		o.DeliveryProvince != previous.DeliveryProvince ||
+		o.DeliveryDistrict != previous.DeliveryDistrict ||
+		floatValue(o.PackageWeight) != floatValue(previous.PackageWeight) ||
+		floatValue(o.PackageVolume) != floatValue(previous.PackageVolume) ||
+		!strings.EqualFold(strings.TrimSpace(o.WeightUnit), strings.TrimSpace(previous.WeightUnit))
+}
+
+// OrderTotal is the breakdown of an order's TotalSalary
+type OrderTotal struct {
+	OrderType   string             `json:"order_type"`
+	Components  map[string]float64 `json:"components"`
+	TotalSalary float64            `json:"total_salary"`
+}
+
+type orderComponent struct {
+	name     string
+	value    float64
+	external bool
+}
+
+// salaryComponents lists the amounts TotalSalary is made of. Components
+// marked external are costs we cover on external orders too; the others are
+// driver pay and fuel, which the contractor covers out of PriceForContractor.
+func (o *Order) salaryComponents() []orderComponent {
+	pointCount := 1
+	if o.PointCount != nil {
+		pointCount = *o.PointCount
+	}
+
+	return []orderComponent{
+		{"trip_salary", floatValue(o.TripSalary), false},
+		{"point_salary", floatValue(o.PointSalary) * float64(pointCount), false},
+		{"daily_salary", floatValue(o.DailySalary), false},
+		{"meal_fee", floatValue(o.MealFee), false},
+		{"oil_fee", floatValue(o.OilFee), false},
+		{"outside_oil_fee", floatValue(o.OutsiteOilFee), false},
+		{"loading_salary", floatValue(o.LoadingSalary), true},
+		{"standby_fee", floatValue(o.StandbyFee), true},
+		{"parking_fee", floatValue(o.ParkingFee), true},
+		{"charge_fee", floatValue(o.ChargeFee), true},
+		{"recovery_fee", floatValue(o.RefundFee), true},
+		{"other_salary", floatValue(o.OtherSalary), true},
+	}
+}
+
+// CalculateTotalSalary derives TotalSalary from the component fields:
+//
+//	internal: trip_salary + point_salary × point_count + daily_salary +
+//	          meal_fee + oil_fee + outside_oil_fee + loading_salary +
+//	          standby_fee + parking_fee + charge_fee + recovery_fee +
+//	          other_salary
+//	external: loading_salary + standby_fee + parking_fee + charge_fee +
+//	          recovery_fee + other_salary
+//
+// An empty OrderType counts as internal. A missing point_count counts as 1.
+// Amounts are in VND and the total is rounded to the dong. Negative amounts
+// are rejected. Driver pay and fuel on an external order are left out of the
+// total, CheckExternalPay rejects them.
+func (o *Order) CalculateTotalSalary() (OrderTotal, error) {
+	orderType := o.OrderType
+	if orderType == "" {
+		orderType = OrderTypeInternal
+	}
+	if orderType != OrderTypeInternal && orderType != OrderTypeExternal {
+		return OrderTotal{}, fmt.Errorf("order_type must be %s or %s", OrderTypeInternal, OrderTypeExternal)
+	}
+	if o.PointCount != nil && *o.PointCount < 0 {
+		return OrderTotal{}, fmt.Errorf("point_count cannot be negative")
+	}
+
+	total := OrderTotal{OrderType: orderType, Components: make(map[string]float64)}
+	for _, component := range o.salaryComponents() {
+		if component.value < 0 {
+			return OrderTotal{}, fmt.Errorf("%s cannot be negative", component.name)
+		}
+		if orderType == OrderTypeExternal && !component.external {
+			continue
+		}
+		total.Components[component.name] = component.value
+		total.TotalSalary += component.value
+	}
+	total.TotalSalary = math.Round(total.TotalSalary)
+
+	return total, nil
+}
+
+// CheckExternalPay rejects driver pay and fuel on an external order, the
+// contractor covers them out of PriceForContractor. Orders saved before the
+// rule can still hold them, updates only check it when ExternalPayChanged.
+func (o *Order) CheckExternalPay() error {
+	if o.OrderType != OrderTypeExternal {
+		return nil
+	}
+	for _, component := range o.salaryComponents() {
+		if !component.external && component.value != 0 {
+			return fmt.Errorf("%s is paid by the contractor on external orders and must be 0", component.name)
+		}
+	}
+	return nil
+}
+
+// ExternalPayChanged reports whether a change to the order affects
+// CheckExternalPay
+func (o *Order) ExternalPayChanged(previous *Order) bool {
+	if o.OrderType != previous.OrderType {
+		return true
+	}
+	current, old := o.salaryComponents(), previous.salaryComponents()
+	for i := range current {
+		if !current[i].external && current[i].value != old[i].value {
+			return true
+		}
+	}
+	return false
+}
+
+func floatValue(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func uuidValue(value *uuid.UUID) uuid.UUID {
+	if value == nil {
+		return uuid.Nil
+	}
+	return *value
 }
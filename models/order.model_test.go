@@ -0,0 +1,201 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func amount(value float64) *float64 {
+	return &value
+}
+
+func count(value int) *int {
+	return &value
+}
+
+func TestCalculateTotalSalary(t *testing.T) {
+	tests := []struct {
+		name  string
+		order Order
+		total float64
+		err   string
+	}{
+		{
+			name:  "empty order",
+			order: Order{},
+			total: 0,
+		},
+		{
+			name: "internal order adds every component",
+			order: Order{
+				OrderType:     OrderTypeInternal,
+				TripSalary:    amount(500000),
+				PointSalary:   amount(50000),
+				PointCount:    count(3),
+				DailySalary:   amount(200000),
+				MealFee:       amount(30000),
+				OilFee:        amount(400000),
+				OutsiteOilFee: amount(100000),
+				LoadingSalary: amount(80000),
+				StandbyFee:    amount(20000),
+				ParkingFee:    amount(10000),
+				ChargeFee:     amount(45000),
+				RefundFee:     amount(5000),
+				OtherSalary:   amount(15000),
+			},
+			total: 500000 + 150000 + 200000 + 30000 + 400000 + 100000 + 80000 + 20000 + 10000 + 45000 + 5000 + 15000,
+		},
+		{
+			name:  "empty order type counts as internal",
+			order: Order{TripSalary: amount(500000)},
+			total: 500000,
+		},
+		{
+			name:  "missing point count counts as one",
+			order: Order{PointSalary: amount(50000)},
+			total: 50000,
+		},
+		{
+			name:  "zero point count",
+			order: Order{PointSalary: amount(50000), PointCount: count(0)},
+			total: 0,
+		},
+		{
+			name: "external order leaves out driver pay and fuel",
+			order: Order{
+				OrderType:     OrderTypeExternal,
+				TripSalary:    amount(500000),
+				OilFee:        amount(400000),
+				LoadingSalary: amount(80000),
+				ChargeFee:     amount(45000),
+			},
+			total: 125000,
+		},
+		{
+			name:  "total is rounded to the dong",
+			order: Order{TripSalary: amount(1000.4), MealFee: amount(0.3)},
+			total: 1001,
+		},
+		{
+			name:  "unknown order type",
+			order: Order{OrderType: "partner"},
+			err:   "order_type must be",
+		},
+		{
+			name:  "negative point count",
+			order: Order{PointCount: count(-1)},
+			err:   "point_count cannot be negative",
+		},
+		{
+			name:  "negative amount",
+			order: Order{ParkingFee: amount(-10000)},
+			err:   "parking_fee cannot be negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			total, err := test.order.CalculateTotalSalary()
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if total.TotalSalary != test.total {
+				t.Errorf("got total %.0f, want %.0f", total.TotalSalary, test.total)
+			}
+		})
+	}
+}
+
+func TestCalculateTotalSalaryComponents(t *testing.T) {
+	order := Order{
+		OrderType:     OrderTypeExternal,
+		TripSalary:    amount(500000),
+		LoadingSalary: amount(80000),
+	}
+
+	total, err := order.CalculateTotalSalary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total.OrderType != OrderTypeExternal {
+		t.Errorf("got order type %q, want %q", total.OrderType, OrderTypeExternal)
+	}
+	if _, ok := total.Components["trip_salary"]; ok {
+		t.Error("trip_salary is listed in the breakdown of an external order")
+	}
+	if total.Components["loading_salary"] != 80000 {
+		t.Errorf("got loading_salary %.0f, want 80000", total.Components["loading_salary"])
+	}
+}
+
+func TestCheckExternalPay(t *testing.T) {
+	internal := Order{OrderType: OrderTypeInternal, TripSalary: amount(500000)}
+	if err := internal.CheckExternalPay(); err != nil {
+		t.Errorf("internal order: unexpected error %v", err)
+	}
+
+	external := Order{OrderType: OrderTypeExternal, LoadingSalary: amount(80000)}
+	if err := external.CheckExternalPay(); err != nil {
+		t.Errorf("external order without driver pay: unexpected error %v", err)
+	}
+
+	external.MealFee = amount(30000)
+	if err := external.CheckExternalPay(); err == nil || !strings.Contains(err.Error(), "meal_fee") {
+		t.Errorf("external order with a meal fee: got error %v, want one about meal_fee", err)
+	}
+}
+
+func TestExternalPayChanged(t *testing.T) {
+	saved := Order{OrderType: OrderTypeExternal, TripSalary: amount(500000), LoadingSalary: amount(80000)}
+
+	edited := saved
+	edited.LoadingSalary = amount(90000)
+	if edited.ExternalPayChanged(&saved) {
+		t.Error("a change to loading_salary counts as a change to driver pay")
+	}
+
+	edited.TripSalary = amount(600000)
+	if !edited.ExternalPayChanged(&saved) {
+		t.Error("a change to trip_salary is not noticed")
+	}
+
+	edited = saved
+	edited.OrderType = OrderTypeInternal
+	if !edited.ExternalPayChanged(&saved) {
+		t.Error("a change of order type is not noticed")
+	}
+}
+
+func TestRouteChanged(t *testing.T) {
+	saved := Order{PickupProvince: "Hà Nội", DeliveryProvince: "Hải Phòng", PackageWeight: amount(3), WeightUnit: "t", PackageVolume: amount(10)}
+
+	tests := []struct {
+		name   string
+		change func(*Order)
+		want   bool
+	}{
+		{name: "nothing", change: func(o *Order) {}, want: false},
+		{name: "notes", change: func(o *Order) { o.Notes = "fragile" }, want: false},
+		{name: "unit case", change: func(o *Order) { o.WeightUnit = " T" }, want: false},
+		{name: "delivery province", change: func(o *Order) { o.DeliveryProvince = "Bắc Ninh" }, want: true},
+		{name: "weight", change: func(o *Order) { o.PackageWeight = amount(4) }, want: true},
+		{name: "weight unit", change: func(o *Order) { o.WeightUnit = "kg" }, want: true},
+		{name: "volume", change: func(o *Order) { o.PackageVolume = nil }, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := saved
+			test.change(&order)
+			if got := order.RouteChanged(&saved); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// LoadVolume is the cargo volume of the truck in m3: Volume, or Length ×
+// Width × Height in metres when no volume is recorded. It is 0 when unknown.
+func (t *Truck) LoadVolume() float64 {
+	if volume := floatValue(t.Volume); volume > 0 {
+		return volume
+	}
+	volume := floatValue(t.Length) * floatValue(t.Width) * floatValue(t.Height)
+	return math.Round(volume*1000) / 1000
+}
+
+// Weight units of the package weight of an order
+const (
+	WeightUnitKg  = "kg"
+	WeightUnitTon = "t"
+)
+
+// weightUnitTons is the number of tons in each accepted weight unit
+var weightUnitTons = map[string]float64{
+	WeightUnitKg:  0.001,
+	WeightUnitTon: 1,
+	"tấn":         1,
+	"ton":         1,
+}
+
+// WeightUnitKnown reports whether the order's weight unit can be converted
+// to tons
+func (o *Order) WeightUnitKnown() bool {
+	_, ok := weightUnitTons[strings.ToLower(strings.TrimSpace(o.WeightUnit))]
+	return ok
+}
+
+// WeightInTons returns the package weight in tons, the unit truck capacity
+// is recorded in. It reports false when the order has no weight or its
+// weight unit is unknown. The billing unit is not a weight unit and is not
+// looked at.
+func (o *Order) WeightInTons() (float64, bool) {
+	tons, known := weightUnitTons[strings.ToLower(strings.TrimSpace(o.WeightUnit))]
+	if o.PackageWeight == nil || *o.PackageWeight <= 0 || !known {
+		return 0, false
+	}
+	return *o.PackageWeight * tons, true
+}
+
+// LoadCheck compares an order's load with the capacity of its truck.
+// Errors mean the truck would be overloaded, warnings that the load could
+// not be checked completely.
+type LoadCheck struct {
+	TruckID      string   `json:"truck_id"`
+	LicensePlate string   `json:"license_plate"`
+	WeightTons   float64  `json:"weight_tons"`
+	CapacityTons float64  `json:"capacity_tons"`
+	Volume       float64  `json:"volume"`
+	TruckVolume  float64  `json:"truck_volume"`
+	Errors       []string `json:"errors,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"`
+}
+
+// Overloaded reports whether the load exceeds the truck's capacity or volume
+func (c LoadCheck) Overloaded() bool {
+	return len(c.Errors) > 0
+}
+
+// CheckTruckLoad checks the order's package weight against the truck's
+// capacity and its package volume against the truck's volume
+func (o *Order) CheckTruckLoad(truck *Truck) LoadCheck {
+	check := LoadCheck{
+		TruckID:      truck.ID.String(),
+		LicensePlate: truck.LicensePlate,
+		CapacityTons: floatValue(truck.Capacity),
+		Volume:       floatValue(o.PackageVolume),
+		TruckVolume:  truck.LoadVolume(),
+	}
+
+	if floatValue(o.PackageWeight) > 0 && !o.WeightUnitKnown() {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("the weight unit %q is not kg or t, the weight was not checked", o.WeightUnit))
+	}
+	if weight, ok := o.WeightInTons(); ok {
+		check.WeightTons = weight
+		switch {
+		case check.CapacityTons <= 0:
+			check.Warnings = append(check.Warnings, fmt.Sprintf("truck %s has no capacity recorded, the weight was not checked", truck.LicensePlate))
+		case weight > check.CapacityTons:
+			check.Errors = append(check.Errors, fmt.Sprintf("%g t exceeds the %g t capacity of truck %s", weight, check.CapacityTons, truck.LicensePlate))
+		}
+	}
+
+	if check.Volume > 0 {
+		switch {
+		case check.TruckVolume <= 0:
+			check.Warnings = append(check.Warnings, fmt.Sprintf("truck %s has no volume or dimensions recorded, the volume was not checked", truck.LicensePlate))
+		case check.Volume > check.TruckVolume:
+			check.Errors = append(check.Errors, fmt.Sprintf("%g m3 exceeds the %g m3 volume of truck %s", check.Volume, check.TruckVolume, truck.LicensePlate))
+		}
+	}
+
+	return check
+}
+
+// LoadChanged reports whether a change to the order affects its load check
+func (o *Order) LoadChanged(previous *Order) bool {
+	return uuidValue(o.TruckID) != uuidValue(previous.TruckID) ||
+		floatValue(o.PackageWeight) != floatValue(previous.PackageWeight) ||
+		floatValue(o.PackageVolume) != floatValue(previous.PackageVolume) ||
+		!strings.EqualFold(strings.TrimSpace(o.WeightUnit), strings.TrimSpace(previous.WeightUnit))
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestWeightInTons(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  Order
+		want   float64
+		wantOK bool
+	}{
+		{name: "kg", order: Order{PackageWeight: amount(2500), WeightUnit: "kg"}, want: 2.5, wantOK: true},
+		{name: "t", order: Order{PackageWeight: amount(3), WeightUnit: "t"}, want: 3, wantOK: true},
+		{name: "tấn with spaces and capitals", order: Order{PackageWeight: amount(3), WeightUnit: " Tấn "}, want: 3, wantOK: true},
+		{name: "ton", order: Order{PackageWeight: amount(1.5), WeightUnit: "ton"}, want: 1.5, wantOK: true},
+		{name: "billing unit is not a weight unit", order: Order{PackageWeight: amount(2500), Unit: "kg", WeightUnit: "t"}, want: 2500, wantOK: true},
+		{name: "no weight unit", order: Order{PackageWeight: amount(3)}},
+		{name: "unknown weight unit", order: Order{PackageWeight: amount(3), WeightUnit: "thùng"}},
+		{name: "no weight", order: Order{WeightUnit: "kg"}},
+		{name: "zero weight", order: Order{PackageWeight: amount(0), WeightUnit: "kg"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := test.order.WeightInTons()
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("got (%g, %v), want (%g, %v)", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckTruckLoad(t *testing.T) {
+	truck := Truck{ID: uuid.New(), LicensePlate: "51C-123.45", Capacity: amount(5), Volume: amount(20)}
+
+	tests := []struct {
+		name     string
+		order    Order
+		truck    Truck
+		weight   float64
+		errors   []string
+		warnings []string
+	}{
+		{
+			name:   "load fits",
+			order:  Order{PackageWeight: amount(4500), WeightUnit: "kg", PackageVolume: amount(15)},
+			truck:  truck,
+			weight: 4.5,
+		},
+		{
+			name:   "capacity is inclusive",
+			order:  Order{PackageWeight: amount(5), WeightUnit: "t", PackageVolume: amount(20)},
+			truck:  truck,
+			weight: 5,
+		},
+		{
+			name:   "too heavy",
+			order:  Order{PackageWeight: amount(6), WeightUnit: "t"},
+			truck:  truck,
+			weight: 6,
+			errors: []string{"6 t exceeds the 5 t capacity of truck 51C-123.45"},
+		},
+		{
+			name:   "too bulky",
+			order:  Order{PackageVolume: amount(25)},
+			truck:  truck,
+			errors: []string{"25 m3 exceeds the 20 m3 volume of truck 51C-123.45"},
+		},
+		{
+			name:  "volume from the dimensions",
+			order: Order{PackageVolume: amount(30)},
+			truck: Truck{LicensePlate: "29H-555.55", Capacity: amount(8), Length: amount(6), Width: amount(2.5), Height: amount(2.5)},
+		},
+		{
+			name:     "truck without capacity",
+			order:    Order{PackageWeight: amount(2), WeightUnit: "t"},
+			truck:    Truck{LicensePlate: "29H-555.55", Volume: amount(20)},
+			weight:   2,
+			warnings: []string{"truck 29H-555.55 has no capacity recorded"},
+		},
+		{
+			name:     "truck without volume or dimensions",
+			order:    Order{PackageVolume: amount(10)},
+			truck:    Truck{LicensePlate: "29H-555.55", Capacity: amount(5)},
+			warnings: []string{"truck 29H-555.55 has no volume or dimensions recorded"},
+		},
+		{
+			name:     "unknown weight unit",
+			order:    Order{PackageWeight: amount(9000), WeightUnit: "thùng"},
+			truck:    truck,
+			warnings: []string{`the weight unit "thùng" is not kg or t`},
+		},
+		{
+			name:  "no load",
+			order: Order{},
+			truck: truck,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := test.order.CheckTruckLoad(&test.truck)
+			if check.WeightTons != test.weight {
+				t.Errorf("got weight %g t, want %g t", check.WeightTons, test.weight)
+			}
+			assertMessages(t, "errors", check.Errors, test.errors)
+			assertMessages(t, "warnings", check.Warnings, test.warnings)
+			if check.Overloaded() != (len(test.errors) > 0) {
+				t.Errorf("Overloaded() = %v with errors %v", check.Overloaded(), check.Errors)
+			}
+		})
+	}
+}
+
+// assertMessages checks that each message starts with the wanted text
+func assertMessages(t *testing.T, kind string, got []string, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("got %s %q, want %q", kind, got, want)
+		return
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("got %s %q, want %q", kind, got, want)
+			return
+		}
+	}
+}
+
+func TestLoadChanged(t *testing.T) {
+	truckID := uuid.New()
+	saved := Order{TruckID: &truckID, PackageWeight: amount(3), WeightUnit: "t", PackageVolume: amount(10)}
+
+	tests := []struct {
+		name   string
+		change func(*Order)
+		want   bool
+	}{
+		{name: "nothing", change: func(o *Order) {}, want: false},
+		{name: "notes", change: func(o *Order) { o.Notes = "fragile" }, want: false},
+		{name: "unit case", change: func(o *Order) { o.WeightUnit = " T" }, want: false},
+		{name: "weight", change: func(o *Order) { o.PackageWeight = amount(4) }, want: true},
+		{name: "weight unit", change: func(o *Order) { o.WeightUnit = "kg" }, want: true},
+		{name: "volume", change: func(o *Order) { o.PackageVolume = nil }, want: true},
+		{name: "truck", change: func(o *Order) { o.TruckID = nil }, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := saved
+			test.change(&order)
+			if got := order.LoadChanged(&saved); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
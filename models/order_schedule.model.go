@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultOrderDuration is how long an order keeps its driver and truck busy
+// when neither an end time nor a later stop time says otherwise
+const DefaultOrderDuration = 8 * time.Hour
+
+// ApplyEndTime fills in the end of the time window the order keeps its
+// driver and truck busy, which starts at OrderTime. An end time that was
+// sent is kept, otherwise it is the latest planned stop time, or
+// DefaultOrderDuration after OrderTime.
+func (o *Order) ApplyEndTime() error {
+	if o.EndTime != nil {
+		if !o.EndTime.After(o.OrderTime) {
+			return fmt.Errorf("end_time must be after order_time")
+		}
+		return nil
+	}
+
+	end := o.OrderTime.Add(DefaultOrderDuration)
+	latest := o.OrderTime
+	for _, stop := range o.Stops {
+		if stop.PlannedAt != nil && stop.PlannedAt.After(latest) {
+			latest = *stop.PlannedAt
+		}
+	}
+	if latest.After(o.OrderTime) {
+		end = latest
+	}
+	o.EndTime = &end
+	return nil
+}
+
+// ScheduleChanged reports whether a change to the order affects who it
+// keeps busy and when
+func (o *Order) ScheduleChanged(previous *Order) bool {
+	return uuidValue(o.DriverID) != uuidValue(previous.DriverID) ||
+		uuidValue(o.TruckID) != uuidValue(previous.TruckID) ||
+		!o.OrderTime.Equal(previous.OrderTime) ||
+		(o.EndTime == nil) != (previous.EndTime == nil) ||
+		(o.EndTime != nil && !o.EndTime.Equal(*previous.EndTime))
+}
+
+// Resources an order can double-book
+const (
+	ResourceDriver = "driver"
+	ResourceTruck  = "truck"
+)
+
+// OrderAssignmentConflict is another order that has the same driver or
+// truck in an overlapping time window
+type OrderAssignmentConflict struct {
+	Resource   string    `json:"resource"`
+	ResourceID uuid.UUID `json:"resource_id"`
+	OrderID    uuid.UUID `json:"order_id"`
+	Status     string    `json:"status"`
+	OrderTime  time.Time `json:"order_time"`
+	EndTime    time.Time `json:"end_time"`
+}
+
+// OrderAssignmentOverride records that an order was saved although it
+// double-books a driver or truck with another order, and who allowed it
+type OrderAssignmentOverride struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	OrderID            uuid.UUID  `gorm:"type:uuid;not null;index" json:"order_id"`
+	ConflictingOrderID uuid.UUID  `gorm:"type:uuid;not null" json:"conflicting_order_id"`
+	Resource           string     `gorm:"type:varchar(20);not null" json:"resource"`
+	ResourceID         uuid.UUID  `gorm:"type:uuid;not null" json:"resource_id"`
+	OverriddenByID     *uuid.UUID `gorm:"type:uuid" json:"overridden_by_id,omitempty"`
+	OverriddenBy       string     `gorm:"size:255" json:"overridden_by,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
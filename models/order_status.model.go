@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Order statuses. An order moves forward through
+// draft → assigned → in_transit → delivered → invoiced
+// and can be cancelled until it is delivered.
+const (
+	OrderStatusDraft     = "draft"
+	OrderStatusAssigned  = "assigned"
+	OrderStatusInTransit = "in_transit"
+	OrderStatusDelivered = "delivered"
+	OrderStatusInvoiced  = "invoiced"
+	OrderStatusCancelled = "cancelled"
+)
+
+// orderStatusTransitions lists the statuses each status can move to.
+// An assigned order can go back to draft when its driver is taken off it.
+var orderStatusTransitions = map[string][]string{
+	OrderStatusDraft:     {OrderStatusAssigned, OrderStatusCancelled},
+	OrderStatusAssigned:  {OrderStatusDraft, OrderStatusInTransit, OrderStatusCancelled},
+	OrderStatusInTransit: {OrderStatusDelivered, OrderStatusCancelled},
+	OrderStatusDelivered: {OrderStatusInvoiced},
+	OrderStatusInvoiced:  {},
+	OrderStatusCancelled: {},
+}
+
+// IsValidOrderStatus reports whether the status exists
+func IsValidOrderStatus(status string) bool {
+	_, ok := orderStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another
+func CanTransitionOrder(from string, to string) bool {
+	for _, status := range orderStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusHistory is one status change of an order. FromStatus is empty
+// for the entry written when the order is created.
+type OrderStatusHistory struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	OrderID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"order_id"`
+	FromStatus  string     `gorm:"type:varchar(20)" json:"from_status"`
+	ToStatus    string     `gorm:"type:varchar(20);not null" json:"to_status"`
+	ChangedByID *uuid.UUID `gorm:"type:uuid" json:"changed_by_id,omitempty"`
+	ChangedBy   string     `json:"changed_by,omitempty"`
+	Note        string     `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt   time.Time  `gorm:"not null;index" json:"created_at"`
+}
+
+type UpdateOrderStatusInput struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
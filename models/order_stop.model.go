@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Order stop types
+const (
+	OrderStopPickup = "pickup"
+	OrderStopDrop   = "drop"
+)
+
+// OrderStop is a place an order picks up or drops cargo at. Stops are
+// visited in Sequence order.
+type OrderStop struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	OrderID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"order_id"`
+	Sequence      int        `gorm:"not null" json:"sequence"`
+	StopType      string     `gorm:"type:varchar(10);not null" json:"stop_type"`
+	Address       string     `gorm:"type:text" json:"address"`
+	Province      string     `gorm:"size:50;not null" json:"province"`
+	District      string     `gorm:"size:50" json:"district"`
+	ContactName   string     `gorm:"size:100" json:"contact_name"`
+	ContactPhone  string     `gorm:"size:20" json:"contact_phone"`
+	PlannedAt     *time.Time `json:"planned_at"`
+	ActualAt      *time.Time `json:"actual_at"`
+	CargoQuantity *float64   `json:"cargo_quantity"`
+	PointSalary   *float64   `json:"point_salary"` // Pay for a drop, the order's stop_point_salary when empty
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ApplyStops numbers the stops in the order they were sent and derives the
+// single route and point fields from them, so pricing, filters and totals
+// keep working on multi-stop orders:
+//
+//	pickup_province, pickup_district:     the first stop, a pickup
+//	delivery_province, delivery_district: the last stop, a drop
+//	point_count:                          the number of drops
+//	point_salary:                         the average pay per drop
+//
+// A drop without its own point_salary is paid the order's stop_point_salary.
+// The base rate is kept apart from the derived average, so saving the order
+// again does not change what the drops are paid. Orders saved without a base
+// rate take it from the point_salary they were sent with. An order without
+// stops keeps these fields as they were sent.
+func (o *Order) ApplyStops() error {
+	if len(o.Stops) == 0 {
+		return nil
+	}
+	if len(o.Stops) < 2 {
+		return fmt.Errorf("an order with stops needs at least a pickup and a drop")
+	}
+	if o.Stops[0].StopType != OrderStopPickup {
+		return fmt.Errorf("the first stop must be a pickup")
+	}
+	if o.Stops[len(o.Stops)-1].StopType != OrderStopDrop {
+		return fmt.Errorf("the last stop must be a drop")
+	}
+
+	if o.StopPointSalary == nil {
+		rate := floatValue(o.PointSalary)
+		o.StopPointSalary = &rate
+	}
+	if *o.StopPointSalary < 0 {
+		return fmt.Errorf("stop_point_salary cannot be negative")
+	}
+
+	rate := *o.StopPointSalary
+	drops, pay := 0, 0.0
+	for i := range o.Stops {
+		stop := &o.Stops[i]
+		// Stops are replaced as a whole, so they are saved as new records
+		stop.ID = uuid.Nil
+		stop.Sequence = i + 1
+		stop.OrderID = o.ID
+
+		switch {
+		case stop.StopType != OrderStopPickup && stop.StopType != OrderStopDrop:
+			return fmt.Errorf("stop %d: stop_type must be %s or %s", stop.Sequence, OrderStopPickup, OrderStopDrop)
+		case stop.Province == "":
+			return fmt.Errorf("stop %d: province is required", stop.Sequence)
+		case floatValue(stop.CargoQuantity) < 0:
+			return fmt.Errorf("stop %d: cargo_quantity cannot be negative", stop.Sequence)
+		case floatValue(stop.PointSalary) < 0:
+			return fmt.Errorf("stop %d: point_salary cannot be negative", stop.Sequence)
+		case stop.StopType == OrderStopPickup && stop.PointSalary != nil:
+			return fmt.Errorf("stop %d: point_salary is only paid for drops", stop.Sequence)
+		}
+
+		if stop.StopType == OrderStopDrop {
+			drops++
+			if stop.PointSalary != nil {
+				pay += *stop.PointSalary
+			} else {
+				pay += rate
+			}
+		}
+	}
+
+	first, last := o.Stops[0], o.Stops[len(o.Stops)-1]
+	o.PickupProvince, o.PickupDistrict = first.Province, first.District
+	o.DeliveryProvince, o.DeliveryDistrict = last.Province, last.District
+
+	average := pay / float64(drops)
+	o.PointCount = &drops
+	o.PointSalary = &average
+	return nil
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func pickup(province string) OrderStop {
+	return OrderStop{StopType: OrderStopPickup, Province: province}
+}
+
+func drop(province string, pay *float64) OrderStop {
+	return OrderStop{StopType: OrderStopDrop, Province: province, PointSalary: pay}
+}
+
+func TestApplyStops(t *testing.T) {
+	order := Order{
+		ID:              uuid.New(),
+		StopPointSalary: amount(100000),
+		Stops: []OrderStop{
+			{ID: uuid.New(), StopType: OrderStopPickup, Province: "Hà Nội", District: "Cầu Giấy"},
+			drop("Bắc Ninh", nil),
+			drop("Hải Dương", amount(160000)),
+			{StopType: OrderStopDrop, Province: "Hải Phòng", District: "Lê Chân"},
+		},
+	}
+
+	if err := order.ApplyStops(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if order.PickupProvince != "Hà Nội" || order.PickupDistrict != "Cầu Giấy" {
+		t.Errorf("got pickup %s, %s, want Hà Nội, Cầu Giấy", order.PickupProvince, order.PickupDistrict)
+	}
+	if order.DeliveryProvince != "Hải Phòng" || order.DeliveryDistrict != "Lê Chân" {
+		t.Errorf("got delivery %s, %s, want Hải Phòng, Lê Chân", order.DeliveryProvince, order.DeliveryDistrict)
+	}
+	if order.PointCount == nil || *order.PointCount != 3 {
+		t.Errorf("got point_count %v, want 3", order.PointCount)
+	}
+	if order.PointSalary == nil || *order.PointSalary != 120000 {
+		t.Errorf("got point_salary %v, want the average 120000", order.PointSalary)
+	}
+	for i, stop := range order.Stops {
+		if stop.Sequence != i+1 {
+			t.Errorf("stop %d: got sequence %d", i, stop.Sequence)
+		}
+		if stop.ID != uuid.Nil {
+			t.Errorf("stop %d: id was kept", i)
+		}
+		if stop.OrderID != order.ID {
+			t.Errorf("stop %d: order_id was not set", i)
+		}
+	}
+
+	total, err := order.CalculateTotalSalary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total.TotalSalary != 360000 {
+		t.Errorf("got total %.0f, want the drops' pay 360000", total.TotalSalary)
+	}
+}
+
+func TestApplyStopsKeepsBaseRate(t *testing.T) {
+	order := Order{
+		PointSalary: amount(100000),
+		Stops: []OrderStop{
+			pickup("Hà Nội"),
+			drop("Bắc Ninh", nil),
+			drop("Hải Phòng", amount(160000)),
+		},
+	}
+
+	// Orders saved without a base rate take it from point_salary
+	if err := order.ApplyStops(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.StopPointSalary == nil || *order.StopPointSalary != 100000 {
+		t.Fatalf("got stop_point_salary %v, want 100000", order.StopPointSalary)
+	}
+	if *order.PointSalary != 130000 {
+		t.Fatalf("got point_salary %.0f, want 130000", *order.PointSalary)
+	}
+
+	// Saving again starts from the stored average, the drops must still be
+	// paid the base rate
+	for i := 0; i < 3; i++ {
+		if err := order.ApplyStops(); err != nil {
+			t.Fatalf("save %d: unexpected error: %v", i+2, err)
+		}
+		if *order.StopPointSalary != 100000 || *order.PointSalary != 130000 {
+			t.Fatalf("save %d: got stop_point_salary %.0f and point_salary %.0f, want 100000 and 130000", i+2, *order.StopPointSalary, *order.PointSalary)
+		}
+	}
+}
+
+func TestApplyStopsWithoutStops(t *testing.T) {
+	order := Order{PickupProvince: "Hà Nội", PointSalary: amount(50000), PointCount: count(2)}
+	if err := order.ApplyStops(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.PickupProvince != "Hà Nội" || *order.PointSalary != 50000 || *order.PointCount != 2 || order.StopPointSalary != nil {
+		t.Error("an order without stops was changed")
+	}
+}
+
+func TestApplyStopsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		order Order
+		err   string
+	}{
+		{
+			name:  "single stop",
+			order: Order{Stops: []OrderStop{pickup("Hà Nội")}},
+			err:   "at least a pickup and a drop",
+		},
+		{
+			name:  "first stop is a drop",
+			order: Order{Stops: []OrderStop{drop("Hà Nội", nil), drop("Hải Phòng", nil)}},
+			err:   "first stop must be a pickup",
+		},
+		{
+			name:  "last stop is a pickup",
+			order: Order{Stops: []OrderStop{pickup("Hà Nội"), pickup("Hải Phòng")}},
+			err:   "last stop must be a drop",
+		},
+		{
+			name:  "unknown stop type",
+			order: Order{Stops: []OrderStop{pickup("Hà Nội"), {StopType: "visit", Province: "Bắc Ninh"}, drop("Hải Phòng", nil)}},
+			err:   "stop 2: stop_type",
+		},
+		{
+			name:  "missing province",
+			order: Order{Stops: []OrderStop{pickup("Hà Nội"), drop("", nil)}},
+			err:   "stop 2: province is required",
+		},
+		{
+			name:  "paid pickup",
+			order: Order{Stops: []OrderStop{{StopType: OrderStopPickup, Province: "Hà Nội", PointSalary: amount(1000)}, drop("Hải Phòng", nil)}},
+			err:   "stop 1: point_salary is only paid for drops",
+		},
+		{
+			name:  "negative drop pay",
+			order: Order{Stops: []OrderStop{pickup("Hà Nội"), drop("Hải Phòng", amount(-1000))}},
+			err:   "stop 2: point_salary cannot be negative",
+		},
+		{
+			name:  "negative base rate",
+			order: Order{StopPointSalary: amount(-1000), Stops: []OrderStop{pickup("Hà Nội"), drop("Hải Phòng", nil)}},
+			err:   "stop_point_salary cannot be negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.order.ApplyStops()
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
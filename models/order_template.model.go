@@ -0,0 +1,394 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DateLayout is how a Date is written
+const DateLayout = "2006-01-02"
+
+// Date is a calendar day without a time or time zone. It is written as
+// YYYY-MM-DD in JSON and stored in a date column.
+type Date struct {
+	time.Time
+}
+
+// DateOf returns the day of t in t's location
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate reads a YYYY-MM-DD day
+func ParseDate(value string) (Date, error) {
+	parsed, err := time.Parse(DateLayout, strings.TrimSpace(value))
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", value)
+	}
+	return Date{parsed}, nil
+}
+
+// AddDays returns the day n days later
+func (d Date) AddDays(n int) Date {
+	return Date{d.Time.AddDate(0, 0, n)}
+}
+
+// At returns the time of day on d in loc
+func (d Date) At(hour int, minute int, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), hour, minute, 0, 0, loc)
+}
+
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("a date must be a YYYY-MM-DD string")
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (Date) GormDataType() string {
+	return "date"
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		*d = DateOf(v)
+		return nil
+	case string:
+		return d.scanString(v)
+	case []byte:
+		return d.scanString(string(v))
+	}
+	return fmt.Errorf("cannot scan %T into a date", value)
+}
+
+func (d *Date) scanString(value string) error {
+	if len(value) > len(DateLayout) {
+		value = value[:len(DateLayout)]
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// WeekdaySet holds ISO weekdays, 1 is Monday and 7 is Sunday. It is stored
+// as a comma separated list.
+type WeekdaySet []int
+
+// Has reports whether the set holds the weekday
+func (s WeekdaySet) Has(day time.Weekday) bool {
+	iso := int(day)
+	if day == time.Sunday {
+		iso = 7
+	}
+	for _, weekday := range s {
+		if weekday == iso {
+			return true
+		}
+	}
+	return false
+}
+
+func (s WeekdaySet) Value() (driver.Value, error) {
+	days := make([]string, len(s))
+	for i, day := range s {
+		days[i] = strconv.Itoa(day)
+	}
+	return strings.Join(days, ","), nil
+}
+
+func (s *WeekdaySet) Scan(value interface{}) error {
+	var list string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		list = v
+	case []byte:
+		list = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into weekdays", value)
+	}
+
+	days := WeekdaySet{}
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		day, err := strconv.Atoi(field)
+		if err != nil {
+			return err
+		}
+		days = append(days, day)
+	}
+	*s = days
+	return nil
+}
+
+// Recurrence rules of an order template
+const (
+	RecurrenceDaily    = "daily"
+	RecurrenceWeekdays = "weekdays" // Monday to Friday
+	RecurrenceWeekly   = "weekly"   // The days listed in Weekdays
+	RecurrenceMonthly  = "monthly"  // MonthDay of every month
+)
+
+// DefaultTemplateLeadDays is how many days ahead a template's orders are
+// created when the template does not say
+const DefaultTemplateLeadDays = 7
+
+// maxTemplateLeadDays keeps a template from filling the schedule with
+// drafts for months ahead
+const maxTemplateLeadDays = 60
+
+// OrderTemplate is a booking a client repeats, the scheduler creates a draft
+// order from it on every day its recurrence rule matches
+type OrderTemplate struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	Name         string     `gorm:"not null" json:"name"`
+	ClientID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"client_id"`
+	Client       Client     `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+	ContractorID uuid.UUID  `gorm:"type:uuid;not null" json:"contractor_id"`
+	Contractor   Contractor `gorm:"foreignKey:ContractorID;references:ID" json:"contractor,omitempty"`
+	DriverID     *uuid.UUID `gorm:"type:uuid" json:"driver_id,omitempty"` // Assigned when free on the day
+	TruckID      *uuid.UUID `gorm:"type:uuid" json:"truck_id,omitempty"`  // Assigned when free on the day and able to carry the load
+
+	OrderType        string   `gorm:"size:20;not null;default:internal" json:"order_type,omitempty"`
+	PickupProvince   string   `gorm:"size:50;not null" json:"pickup_province"`
+	PickupDistrict   string   `gorm:"size:50" json:"pickup_district"`
+	DeliveryProvince string   `gorm:"size:50;not null" json:"delivery_province"`
+	DeliveryDistrict string   `gorm:"size:50" json:"delivery_district"`
+	Unit             string   `gorm:"size:20;not null" json:"unit"`
+	PackageWeight    *float64 `json:"package_weight"`
+	WeightUnit       string   `gorm:"size:10;not null;default:''" json:"weight_unit"`
+	PackageVolume    *float64 `json:"package_volumn"`
+	TripCount        int      `gorm:"not null;default:1" json:"trip_count"`
+	TripSalary       *float64 `gorm:"not null;default:0" json:"trip_salary"`
+	DailySalary      *float64 `gorm:"not null;default:0" json:"daily_salary"`
+	PointCount       *int     `gorm:"not null;default:1" json:"point_count"`
+	PointSalary      *float64 `gorm:"not null;default:0" json:"point_salary"`
+	RefundFee        *float64 `gorm:"not null;default:0" json:"recovery_fee"`
+	LoadingSalary    *float64 `gorm:"not null;default:0" json:"loading_salary"`
+	MealFee          *float64 `gorm:"not null;default:0" json:"meal_fee"`
+	StandbyFee       *float64 `gorm:"not null;default:0" json:"standby_fee"`
+	ParkingFee       *float64 `gorm:"not null;default:0" json:"parking_fee"`
+	OtherSalary      *float64 `gorm:"not null;default:0" json:"other_salary"`
+	OutsiteOilFee    *float64 `gorm:"not null;default:0" json:"outside_oil_fee"`
+	OilFee           *float64 `gorm:"not null;default:0" json:"oil_fee"`
+	ChargeFee        *float64 `gorm:"not null;default:0" json:"charge_fee"`
+	Notes            string   `gorm:"type:text" json:"notes"`
+
+	Recurrence string     `gorm:"size:20;not null" json:"recurrence"`
+	Weekdays   WeekdaySet `gorm:"type:varchar(20)" json:"weekdays,omitempty"` // ISO weekdays of a weekly template
+	MonthDay   int        `json:"month_day,omitempty"`                        // Day of a monthly template, the last day in shorter months
+	OrderAt    string     `gorm:"size:5;not null" json:"order_at"`            // Time of day of the orders, HH:MM
+	StartDate  Date       `gorm:"not null" json:"start_date"`
+	EndDate    *Date      `json:"end_date,omitempty"`
+	LeadDays   int        `gorm:"not null" json:"lead_days"` // How many days ahead the orders are created
+	Paused     bool       `gorm:"not null;default:false" json:"paused"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// HideSalary clears the driver pay and fees of the template, so they are
+// not exposed to roles without access to payroll data
+func (t *OrderTemplate) HideSalary() {
+	t.TripSalary, t.DailySalary, t.PointSalary = nil, nil, nil
+	t.RefundFee, t.LoadingSalary, t.MealFee = nil, nil, nil
+	t.StandbyFee, t.ParkingFee, t.OtherSalary = nil, nil, nil
+	t.OutsiteOilFee, t.OilFee, t.ChargeFee = nil, nil, nil
+}
+
+// Validate checks the template and fills in the lead time when none is set
+func (t *OrderTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if t.ClientID == uuid.Nil || t.ContractorID == uuid.Nil {
+		return fmt.Errorf("client_id and contractor_id are required")
+	}
+	if strings.TrimSpace(t.PickupProvince) == "" || strings.TrimSpace(t.DeliveryProvince) == "" {
+		return fmt.Errorf("pickup_province and delivery_province are required")
+	}
+
+	switch t.Recurrence {
+	case RecurrenceDaily, RecurrenceWeekdays:
+	case RecurrenceWeekly:
+		if len(t.Weekdays) == 0 {
+			return fmt.Errorf("a weekly template needs weekdays, 1 is Monday and 7 is Sunday")
+		}
+		for _, day := range t.Weekdays {
+			if day < 1 || day > 7 {
+				return fmt.Errorf("weekdays go from 1 for Monday to 7 for Sunday")
+			}
+		}
+		sort.Ints(t.Weekdays)
+	case RecurrenceMonthly:
+		if t.MonthDay < 1 || t.MonthDay > 31 {
+			return fmt.Errorf("a monthly template needs a month_day from 1 to 31")
+		}
+	default:
+		return fmt.Errorf("recurrence must be %s, %s, %s or %s", RecurrenceDaily, RecurrenceWeekdays, RecurrenceWeekly, RecurrenceMonthly)
+	}
+
+	if _, _, err := t.orderClock(); err != nil {
+		return err
+	}
+	if t.StartDate.IsZero() {
+		return fmt.Errorf("start_date is required")
+	}
+	if t.EndDate != nil && t.EndDate.Before(t.StartDate.Time) {
+		return fmt.Errorf("end_date cannot be before start_date")
+	}
+
+	if t.LeadDays == 0 {
+		t.LeadDays = DefaultTemplateLeadDays
+	}
+	if t.LeadDays < 0 || t.LeadDays > maxTemplateLeadDays {
+		return fmt.Errorf("lead_days must be from 1 to %d", maxTemplateLeadDays)
+	}
+
+	order := t.NewOrder(t.StartDate, time.Local)
+	if err := order.CheckExternalPay(); err != nil {
+		return err
+	}
+	_, err := order.CalculateTotalSalary()
+	return err
+}
+
+// orderClock reads the time of day of the template's orders
+func (t *OrderTemplate) orderClock() (int, int, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(t.OrderAt))
+	if err != nil {
+		return 0, 0, fmt.Errorf("order_at must be a time of day as HH:MM")
+	}
+	return clock.Hour(), clock.Minute(), nil
+}
+
+// OccursOn reports whether the template books an order on the day
+func (t *OrderTemplate) OccursOn(day Date) bool {
+	if day.Before(t.StartDate.Time) || (t.EndDate != nil && day.After(t.EndDate.Time)) {
+		return false
+	}
+
+	switch t.Recurrence {
+	case RecurrenceDaily:
+		return true
+	case RecurrenceWeekdays:
+		return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+	case RecurrenceWeekly:
+		return t.Weekdays.Has(day.Weekday())
+	case RecurrenceMonthly:
+		lastDay := day.AddDays(-day.Day()+1).Time.AddDate(0, 1, -1).Day()
+		if t.MonthDay > lastDay {
+			return day.Day() == lastDay
+		}
+		return day.Day() == t.MonthDay
+	}
+	return false
+}
+
+// NewOrder builds the draft order the template books on the day, with the
+// order time in loc. Prices, the total and the end time are left to the
+// caller as for any new order.
+func (t *OrderTemplate) NewOrder(day Date, loc *time.Location) Order {
+	hour, minute, _ := t.orderClock()
+	return Order{
+		ID:               uuid.New(),
+		ContractorID:     t.ContractorID,
+		ClientID:         t.ClientID,
+		DriverID:         t.DriverID,
+		TruckID:          t.TruckID,
+		OrderType:        t.OrderType,
+		Status:           OrderStatusDraft,
+		OrderTime:        day.At(hour, minute, loc),
+		PickupProvince:   t.PickupProvince,
+		PickupDistrict:   t.PickupDistrict,
+		DeliveryProvince: t.DeliveryProvince,
+		DeliveryDistrict: t.DeliveryDistrict,
+		Unit:             t.Unit,
+		WeightUnit:       t.WeightUnit,
+		PackageWeight:    t.PackageWeight,
+		PackageVolume:    t.PackageVolume,
+		TripCount:        t.TripCount,
+		TripSalary:       t.TripSalary,
+		DailySalary:      t.DailySalary,
+		PointCount:       t.PointCount,
+		PointSalary:      t.PointSalary,
+		RefundFee:        t.RefundFee,
+		LoadingSalary:    t.LoadingSalary,
+		MealFee:          t.MealFee,
+		StandbyFee:       t.StandbyFee,
+		ParkingFee:       t.ParkingFee,
+		OtherSalary:      t.OtherSalary,
+		OutsiteOilFee:    t.OutsiteOilFee,
+		OilFee:           t.OilFee,
+		ChargeFee:        t.ChargeFee,
+		Notes:            t.Notes,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+}
+
+// Holiday is a day no orders are generated from templates
+type Holiday struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	Date      Date      `gorm:"not null;uniqueIndex" json:"date"`
+	Name      string    `gorm:"not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateHolidayInput struct {
+	Date Date   `json:"date" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+// Results of an order template run
+const (
+	TemplateRunCreated = "created" // The draft order was created
+	TemplateRunHoliday = "holiday" // The day is a holiday, it is booked when the holiday is removed
+	TemplateRunFailed  = "failed"  // The order could not be created, the day is tried again
+)
+
+// OrderTemplateRun logs what the scheduler did for one day of a template.
+// There is one run per template and day, so a day is never booked twice.
+type OrderTemplateRun struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	TemplateID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_order_template_runs_day" json:"template_id"`
+	Date       Date       `gorm:"not null;uniqueIndex:idx_order_template_runs_day;index" json:"date"`
+	Result     string     `gorm:"type:varchar(20);not null" json:"result"`
+	OrderID    *uuid.UUID `gorm:"type:uuid" json:"order_id,omitempty"`
+	Message    string     `gorm:"type:text" json:"message,omitempty"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import "testing"
+
+func mustParseDate(t *testing.T, value string) Date {
+	t.Helper()
+	day, err := ParseDate(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return day
+}
+
+func TestOccursOn(t *testing.T) {
+	endDate := mustParseDate(t, "2024-06-30")
+
+	tests := []struct {
+		name     string
+		template OrderTemplate
+		days     map[string]bool
+	}{
+		{
+			name:     "daily",
+			template: OrderTemplate{Recurrence: RecurrenceDaily},
+			days:     map[string]bool{"2024-03-15": true, "2024-03-16": true, "2024-03-17": true},
+		},
+		{
+			name:     "weekdays",
+			template: OrderTemplate{Recurrence: RecurrenceWeekdays},
+			days:     map[string]bool{"2024-03-15": true, "2024-03-16": false, "2024-03-17": false, "2024-03-18": true},
+		},
+		{
+			name:     "weekly on Monday and Sunday",
+			template: OrderTemplate{Recurrence: RecurrenceWeekly, Weekdays: WeekdaySet{1, 7}},
+			days:     map[string]bool{"2024-03-17": true, "2024-03-18": true, "2024-03-19": false, "2024-03-23": false},
+		},
+		{
+			name:     "monthly",
+			template: OrderTemplate{Recurrence: RecurrenceMonthly, MonthDay: 15},
+			days:     map[string]bool{"2024-03-15": true, "2024-04-15": true, "2024-03-14": false, "2024-03-16": false},
+		},
+		{
+			name:     "monthly past the end of short months",
+			template: OrderTemplate{Recurrence: RecurrenceMonthly, MonthDay: 31},
+			days:     map[string]bool{"2024-02-29": true, "2024-02-28": false, "2024-03-31": true, "2024-03-30": false, "2024-04-30": true},
+		},
+		{
+			name:     "monthly on the 29th in February of a common year",
+			template: OrderTemplate{Recurrence: RecurrenceMonthly, MonthDay: 29, StartDate: mustParseDate(t, "2023-01-01")},
+			days:     map[string]bool{"2023-02-28": true, "2023-02-27": false, "2023-03-29": true, "2023-03-31": false},
+		},
+		{
+			name:     "start and end dates are inclusive",
+			template: OrderTemplate{Recurrence: RecurrenceDaily, StartDate: mustParseDate(t, "2024-03-01"), EndDate: &endDate},
+			days:     map[string]bool{"2024-02-29": false, "2024-03-01": true, "2024-06-30": true, "2024-07-01": false},
+		},
+		{
+			name:     "unknown recurrence",
+			template: OrderTemplate{Recurrence: "yearly"},
+			days:     map[string]bool{"2024-03-15": false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.template.StartDate.IsZero() {
+				test.template.StartDate = mustParseDate(t, "2024-01-01")
+			}
+			for value, want := range test.days {
+				if got := test.template.OccursOn(mustParseDate(t, value)); got != want {
+					t.Errorf("OccursOn(%s) = %v, want %v", value, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of proof of delivery files
+const (
+	PODFilePhoto     = "photo"
+	PODFileSignature = "signature"
+)
+
+// OrderPOD is a proof of delivery of an order: who received the cargo,
+// when, and the photos and signature taken on delivery
+type OrderPOD struct {
+	ID           uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	OrderID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"order_id"`
+	ReceiverName string         `gorm:"size:100;not null" json:"receiver_name"`
+	DeliveredAt  time.Time      `gorm:"not null" json:"delivered_at"`
+	Notes        string         `gorm:"type:text" json:"notes,omitempty"`
+	RecordedByID *uuid.UUID     `gorm:"type:uuid" json:"recorded_by_id,omitempty"`
+	RecordedBy   string         `gorm:"size:255" json:"recorded_by,omitempty"`
+	Files        []OrderPODFile `gorm:"foreignKey:PODID;constraint:OnDelete:CASCADE" json:"files"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// OrderPODFile is a photo or signature image of a proof of delivery. The
+// file itself is kept by the file storage under FileName.
+type OrderPODFile struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	PODID        uuid.UUID `gorm:"column:pod_id;type:uuid;not null;index" json:"pod_id"`
+	Kind         string    `gorm:"type:varchar(20);not null" json:"kind"`
+	FileName     string    `gorm:"not null" json:"file_name"`
+	OriginalName string    `json:"original_name"`
+	ContentType  string    `gorm:"size:100" json:"content_type"`
+	Size         int64     `json:"size"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreateOrderPODInput holds the form fields of a proof of delivery upload,
+// the photos and signature are sent as files next to them
+type CreateOrderPODInput struct {
+	ReceiverName string     `form:"receiver_name" binding:"required"`
+	DeliveredAt  *time.Time `form:"delivered_at" time_format:"2006-01-02T15:04:05Z07:00"`
+	Notes        string     `form:"notes"`
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,12 +18,27 @@ type CreatePricing struct {
 	OwnerType string        `gorm:"not null;index" json:"owner_type"`
 }
 
+// UpdatePricing changes the sent fields of a pricing. Sent prices replace
+// its price details, details keep their ID when it is sent.
+type UpdatePricing struct {
+	FileName *string        `json:"file_name"`
+	Prices   *[]PriceDetail `json:"prices"`
+	Version  int            `json:"version"`
+}
+
+// Owner types of a pricing table
+const (
+	PricingOwnerClient     = "client"
+	PricingOwnerContractor = "contractor"
+)
+
 // Pricing represents the pricing structure in the system
 type Pricing struct {
 	ID           uuid.UUID     `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
 	FileName     string        `gorm:"not null" json:"file_name"`
 	PriceDetails []PriceDetail `gorm:"foreignkey:PricingID" json:"price_details"` // One-to-many relationship
 	CreatedAt    time.Time     `json:"created_at"`
+	Version      int           `gorm:"not null;default:1" json:"version"` // Raised on every update, see utils.ExpectedVersion
 	UpdatedAt    time.Time     `json:"updated_at"`
 
 	OwnerID   uuid.UUID `gorm:"type:uuid;index" json:"owner_id,omitempty"`
@@ -39,9 +56,103 @@ type PriceDetail struct {
 	WeightPrices     JSONBMap  `gorm:"type:jsonb" json:"weight_prices"`
 }
 
+// PriceWildcard in a route field of a price detail matches any value
+const PriceWildcard = "-"
+
+// RouteSpecificity reports whether the detail covers the route, and how many
+// of the route fields it names explicitly. Wildcards cover any value, so a
+// more specific detail wins over a catch-all one. It returns -1 when the
+// detail does not cover the route.
+func (d *PriceDetail) RouteSpecificity(pickupProvince string, pickupDistrict string, deliveryProvince string, deliveryDistrict string) int {
+	specificity := 0
+	for _, field := range [][2]string{
+		{d.PickupProvince, pickupProvince},
+		{d.PickupDistrict, pickupDistrict},
+		{d.DeliveryProvince, deliveryProvince},
+		{d.DeliveryDistrict, deliveryDistrict},
+	} {
+		rule := normalizePlace(field[0])
+		if rule == "" || rule == PriceWildcard {
+			continue
+		}
+		if rule != normalizePlace(field[1]) {
+			return -1
+		}
+		specificity++
+	}
+	return specificity
+}
+
+func normalizePlace(place string) string {
+	return strings.ToLower(strings.Join(strings.Fields(place), " "))
+}
+
 // Custom type to handle map in JSONB format
 type JSONBMap map[string]float64
 
+// Tier picks the price of a load from weight tiers. Keys are the upper
+// bound of their tier, a number with an optional unit: "1.5", "3.5T",
+// "3.5 tấn" or "2000kg" for weight, "10m3" or "10cbm" for volume. Weight
+// tiers are compared in tons, a number without a unit is in tons as well.
+// The smallest tier the load fits in wins. Volume tiers are used when the
+// load has no weight. A map with a single key that is not a tier, such as
+// "-", is a flat price.
+func (j JSONBMap) Tier(weightTons float64, volume *float64) (string, float64, bool) {
+	if len(j) == 1 {
+		for key, price := range j {
+			if _, _, ok := parseTier(key); !ok {
+				return key, price, true
+			}
+		}
+	}
+
+	measure, byVolume := weightTons, false
+	if measure <= 0 {
+		measure, byVolume = floatValue(volume), true
+	}
+	if measure <= 0 {
+		return "", 0, false
+	}
+
+	bestKey, bestBound, found := "", 0.0, false
+	for key := range j {
+		bound, isVolume, ok := parseTier(key)
+		if !ok || isVolume != byVolume || bound < measure {
+			continue
+		}
+		if !found || bound < bestBound {
+			bestKey, bestBound, found = key, bound, true
+		}
+	}
+	if !found {
+		return "", 0, false
+	}
+	return bestKey, j[bestKey], true
+}
+
+// parseTier reads the upper bound of a tier key, in tons for weight tiers
+// and m3 for volume tiers, and whether it is a volume tier
+func parseTier(key string) (float64, bool, bool) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	isVolume, scale := false, 1.0
+	for _, suffix := range []string{"m3", "cbm", "khối", "kg", "tấn", "t"} {
+		if strings.HasSuffix(key, suffix) {
+			isVolume = suffix == "m3" || suffix == "cbm" || suffix == "khối"
+			if suffix == "kg" {
+				scale = 0.001
+			}
+			key = strings.TrimSpace(strings.TrimSuffix(key, suffix))
+			break
+		}
+	}
+
+	bound, err := strconv.ParseFloat(strings.ReplaceAll(key, ",", "."), 64)
+	if err != nil || bound <= 0 {
+		return 0, false, false
+	}
+	return bound * scale, isVolume, true
+}
+
 // Scan implements the Scanner interface to handle the JSONB type
 func (j *JSONBMap) Scan(value interface{}) error {
 	// If value is nil, initialize as an empty map
@@ -58,3 +169,53 @@ func (j JSONBMap) Value() (driver.Value, error) {
 	// Marshal the map to JSON and store it as []byte
 	return json.Marshal(j)
 }
+
+// Custom type to handle a list of strings in JSONB format
+type JSONBStringList []string
+
+// Scan implements the Scanner interface to handle the JSONB type
+func (j *JSONBStringList) Scan(value interface{}) error {
+	if value == nil {
+		*j = JSONBStringList{}
+		return nil
+	}
+	return json.Unmarshal(value.([]byte), j)
+}
+
+// Value implements the Valuer interface to store the list as JSONB in PostgreSQL
+func (j JSONBStringList) Value() (driver.Value, error) {
+	if j == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal(j)
+}
+
+// Contains reports whether the list holds the value
+func (j JSONBStringList) Contains(value string) bool {
+	for _, item := range j {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Custom type to handle an arbitrary JSON object in JSONB format
+type JSONBObject map[string]interface{}
+
+// Scan implements the Scanner interface to handle the JSONB type
+func (j *JSONBObject) Scan(value interface{}) error {
+	if value == nil {
+		*j = nil
+		return nil
+	}
+	return json.Unmarshal(value.([]byte), j)
+}
+
+// Value implements the Valuer interface, a nil object is stored as NULL
+func (j JSONBObject) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return json.Marshal(j)
+}
@@ -0,0 +1,93 @@
+package models
+
+import "testing"
+
+func TestRouteSpecificity(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail PriceDetail
+		want   int
+	}{
+		{
+			name:   "every field named",
+			detail: PriceDetail{PickupProvince: "Hà Nội", PickupDistrict: "Cầu Giấy", DeliveryProvince: "Hải Phòng", DeliveryDistrict: "Lê Chân"},
+			want:   4,
+		},
+		{
+			name:   "wildcards cover any value",
+			detail: PriceDetail{PickupProvince: "Hà Nội", PickupDistrict: PriceWildcard, DeliveryProvince: "Hải Phòng", DeliveryDistrict: PriceWildcard},
+			want:   2,
+		},
+		{
+			name:   "empty fields cover any value",
+			detail: PriceDetail{PickupProvince: "Hà Nội"},
+			want:   1,
+		},
+		{
+			name:   "catch-all detail",
+			detail: PriceDetail{PickupProvince: PriceWildcard, PickupDistrict: PriceWildcard, DeliveryProvince: PriceWildcard, DeliveryDistrict: PriceWildcard},
+			want:   0,
+		},
+		{
+			name:   "case and spacing are ignored",
+			detail: PriceDetail{PickupProvince: "  hà   nội ", DeliveryProvince: "HẢI PHÒNG"},
+			want:   2,
+		},
+		{
+			name:   "other province",
+			detail: PriceDetail{PickupProvince: "Hà Nội", DeliveryProvince: "Đà Nẵng"},
+			want:   -1,
+		},
+		{
+			name:   "other district",
+			detail: PriceDetail{PickupProvince: "Hà Nội", PickupDistrict: "Đống Đa"},
+			want:   -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.detail.RouteSpecificity("Hà Nội", "Cầu Giấy", "Hải Phòng", "Lê Chân")
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTier(t *testing.T) {
+	weightTiers := JSONBMap{"1.5": 1000000, "3.5T": 1500000, "5 tấn": 2000000, "8000kg": 2500000}
+	volumeTiers := JSONBMap{"10m3": 900000, "20cbm": 1400000, "30 khối": 1800000}
+
+	tests := []struct {
+		name      string
+		tiers     JSONBMap
+		weight    float64
+		volume    *float64
+		wantKey   string
+		wantPrice float64
+		wantOK    bool
+	}{
+		{name: "smallest tier that fits", tiers: weightTiers, weight: 1.2, wantKey: "1.5", wantPrice: 1000000, wantOK: true},
+		{name: "bound is inclusive", tiers: weightTiers, weight: 3.5, wantKey: "3.5T", wantPrice: 1500000, wantOK: true},
+		{name: "tấn suffix", tiers: weightTiers, weight: 4, wantKey: "5 tấn", wantPrice: 2000000, wantOK: true},
+		{name: "kg tier is converted to tons", tiers: weightTiers, weight: 7.5, wantKey: "8000kg", wantPrice: 2500000, wantOK: true},
+		{name: "kg tier does not catch light loads", tiers: JSONBMap{"500kg": 300000, "2": 800000}, weight: 0.6, wantKey: "2", wantPrice: 800000, wantOK: true},
+		{name: "too heavy for every tier", tiers: weightTiers, weight: 9},
+		{name: "volume is used without a weight", tiers: volumeTiers, volume: amount(15), wantKey: "20cbm", wantPrice: 1400000, wantOK: true},
+		{name: "weight tiers ignore volume", tiers: weightTiers, volume: amount(2)},
+		{name: "volume tiers ignore weight", tiers: volumeTiers, weight: 2},
+		{name: "no load", tiers: weightTiers},
+		{name: "flat price", tiers: JSONBMap{"-": 700000}, wantKey: "-", wantPrice: 700000, wantOK: true},
+		{name: "decimal comma", tiers: JSONBMap{"2,5": 1200000}, weight: 2.5, wantKey: "2,5", wantPrice: 1200000, wantOK: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, price, ok := test.tiers.Tier(test.weight, test.volume)
+			if key != test.wantKey || price != test.wantPrice || ok != test.wantOK {
+				t.Errorf("got (%q, %.0f, %v), want (%q, %.0f, %v)", key, price, ok, test.wantKey, test.wantPrice, test.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package models
+
+import "time"
+
+// Roles that can be assigned to a user
+const (
+	RoleAdmin      = "admin"
+	RoleDispatcher = "dispatcher"
+	RoleAccountant = "accountant"
+	RoleDriver     = "driver"
+	RoleContractor = "contractor"
+	RoleReadOnly   = "read_only"
+)
+
+// Permissions are written as "<resource>:<action>"
+const (
+	PermUsersRead        = "users:read"
+	PermUsersWrite       = "users:write"
+	PermPostsRead        = "posts:read"
+	PermPostsWrite       = "posts:write"
+	PermContractorsRead  = "contractors:read"
+	PermContractorsWrite = "contractors:write"
+	PermDriversRead      = "drivers:read"
+	PermDriversWrite     = "drivers:write"
+	PermTrucksRead       = "trucks:read"
+	PermTrucksWrite      = "trucks:write"
+	PermPricingRead      = "pricing:read"
+	PermPricingWrite     = "pricing:write"
+	PermFilesRead        = "files:read"
+	PermFilesWrite       = "files:write"
+	PermOrdersRead       = "orders:read"
+	PermOrdersWrite      = "orders:write"
+	PermOrdersOverride   = "orders:override" // Save orders that double-book a driver or truck
+	PermPayslipsRead     = "payslips:read"
+	PermPayslipsWrite    = "payslips:write"
+	PermSalaryRead       = "salary:read"  // See the driver pay and fees on orders and the fixed salary of drivers
+	PermSalaryWrite      = "salary:write" // Set the driver pay and fees on orders and the fixed salary of drivers
+	PermClientsRead      = "clients:read"
+	PermClientsWrite     = "clients:write"
+	PermSettingsRead     = "settings:read"
+	PermSettingsWrite    = "settings:write"
+	PermAPIKeysManage    = "api_keys:manage"
+	PermAuditRead        = "audit:read"
+	PermTrashPurge       = "trash:purge" // Permanently delete records from the trash
+)
+
+// AllPermissions lists every permission known by the system
+var AllPermissions = []string{
+	PermUsersRead, PermUsersWrite,
+	PermPostsRead, PermPostsWrite,
+	PermContractorsRead, PermContractorsWrite,
+	PermDriversRead, PermDriversWrite,
+	PermTrucksRead, PermTrucksWrite,
+	PermPricingRead, PermPricingWrite,
+	PermFilesRead, PermFilesWrite,
+	PermOrdersRead, PermOrdersWrite, PermOrdersOverride,
+	PermPayslipsRead, PermPayslipsWrite,
+	PermSalaryRead, PermSalaryWrite,
+	PermClientsRead, PermClientsWrite,
+	PermSettingsRead, PermSettingsWrite,
+	PermAPIKeysManage,
+	PermAuditRead,
+	PermTrashPurge,
+}
+
+// RolePermissions maps each role to the permissions it grants.
+// Admins are granted every permission and are not listed here.
+// Payroll (payslips and salaries) and pricing data are kept away from
+// dispatchers.
+var RolePermissions = map[string][]string{
+	RoleDispatcher: {
+		PermPostsRead, PermPostsWrite,
+		PermContractorsRead, PermContractorsWrite,
+		PermDriversRead, PermDriversWrite,
+		PermTrucksRead, PermTrucksWrite,
+		PermFilesRead, PermFilesWrite,
+		PermOrdersRead, PermOrdersWrite,
+		PermClientsRead, PermClientsWrite,
+		PermSettingsRead,
+	},
+	RoleAccountant: {
+		PermPostsRead,
+		PermContractorsRead,
+		PermDriversRead,
+		PermTrucksRead,
+		PermPricingRead, PermPricingWrite,
+		PermFilesRead, PermFilesWrite,
+		PermOrdersRead, PermOrdersWrite,
+		PermPayslipsRead, PermPayslipsWrite,
+		PermSalaryRead, PermSalaryWrite,
+		PermClientsRead,
+		PermSettingsRead, PermSettingsWrite,
+	},
+	RoleDriver:     {},
+	RoleContractor: {},
+	RoleReadOnly: {
+		PermPostsRead,
+		PermContractorsRead,
+		PermDriversRead,
+		PermTrucksRead,
+		PermFilesRead,
+		PermOrdersRead,
+		PermClientsRead,
+		PermSettingsRead,
+	},
+}
+
+// IsValidRole reports whether the given role exists
+func IsValidRole(role string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether the role grants the permission
+func HasPermission(role string, permission string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionsForRole returns every permission granted to the role
+func PermissionsForRole(role string) []string {
+	if role == RoleAdmin {
+		return AllPermissions
+	}
+	return RolePermissions[role]
+}
+
+type UpdateRoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// RolePolicy holds the security settings an admin chose for a role
+type RolePolicy struct {
+	Role             string    `gorm:"type:varchar(255);primary_key" json:"role"`
+	RequireTwoFactor bool      `gorm:"not null;default:false" json:"require_two_factor"`
+	UpdatedAt        time.Time `gorm:"not null" json:"updated_at"`
+}
+
+type UpdateRolePolicyInput struct {
+	RequireTwoFactor *bool `json:"require_two_factor" binding:"required"`
+}
+
+type RoleResponse struct {
+	Role             string   `json:"role"`
+	Permissions      []string `json:"permissions"`
+	RequireTwoFactor bool     `json:"require_two_factor"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleAdmin, PermUsersWrite, true},
+		{RoleAdmin, PermPayslipsWrite, true},
+		{RoleDispatcher, PermOrdersWrite, true},
+		{RoleDispatcher, PermDriversWrite, true},
+		{RoleDispatcher, PermPayslipsRead, false},
+		{RoleDispatcher, PermPricingRead, false},
+		{RoleDispatcher, PermUsersRead, false},
+		{RoleDispatcher, PermSalaryRead, false},
+		{RoleDispatcher, PermSalaryWrite, false},
+		{RoleAccountant, PermPayslipsWrite, true},
+		{RoleAccountant, PermPricingWrite, true},
+		{RoleAccountant, PermSalaryRead, true},
+		{RoleAccountant, PermSalaryWrite, true},
+		{RoleAccountant, PermDriversWrite, false},
+		{RoleAccountant, PermUsersWrite, false},
+		{RoleReadOnly, PermOrdersRead, true},
+		{RoleReadOnly, PermOrdersWrite, false},
+		{RoleReadOnly, PermSalaryRead, false},
+		{RoleDriver, PermOrdersRead, false},
+		{"user", PermOrdersRead, false},
+		{"", PermPostsRead, false},
+	}
+
+	for _, test := range tests {
+		if got := HasPermission(test.role, test.permission); got != test.want {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", test.role, test.permission, got, test.want)
+		}
+	}
+}
+
+func TestRolePermissionsAreKnown(t *testing.T) {
+	known := make(map[string]bool)
+	for _, permission := range AllPermissions {
+		known[permission] = true
+	}
+
+	for role, permissions := range RolePermissions {
+		if role == RoleAdmin {
+			t.Error("admins are granted every permission and must not be listed")
+		}
+		for _, permission := range permissions {
+			if !known[permission] {
+				t.Errorf("%s is granted %q, which is not in AllPermissions", role, permission)
+			}
+		}
+	}
+}
+
+func TestReadOnlyCannotWrite(t *testing.T) {
+	for _, permission := range RolePermissions[RoleReadOnly] {
+		if strings.HasSuffix(permission, ":write") {
+			t.Errorf("read_only is granted %q", permission)
+		}
+	}
+}
+
+func TestIsValidRole(t *testing.T) {
+	for _, role := range []string{RoleAdmin, RoleDispatcher, RoleAccountant, RoleDriver, RoleReadOnly} {
+		if !IsValidRole(role) {
+			t.Errorf("IsValidRole(%q) = false", role)
+		}
+	}
+	for _, role := range []string{"", "user", "Admin", "superuser"} {
+		if IsValidRole(role) {
+			t.Errorf("IsValidRole(%q) = true", role)
+		}
+	}
+}
+
+func TestPermissionsForRole(t *testing.T) {
+	if got := PermissionsForRole(RoleAdmin); len(got) != len(AllPermissions) {
+		t.Errorf("admin has %d permissions, want all %d", len(got), len(AllPermissions))
+	}
+	if got := PermissionsForRole(RoleDriver); len(got) != 0 {
+		t.Errorf("driver has permissions %v, want none", got)
+	}
+	if got := PermissionsForRole("unknown"); got != nil {
+		t.Errorf("an unknown role has permissions %v", got)
+	}
+}
@@ -10,5 +10,6 @@ type Setting struct {
 	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
 	Settings  JSONBMap  `gorm:"type:jsonb" json:"settings"`
 	CreatedAt time.Time `gorm:"not null" json:"created_at,omitempty"`
+	Version   int       `gorm:"not null;default:1" json:"version"` // Raised on every update, see utils.ExpectedVersion
 	UpdatedAt time.Time `gorm:"not null" json:"updated_at,omitempty"`
 }
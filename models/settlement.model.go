@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Settlement states. A disputed settlement can still be confirmed once the
+// figures have been corrected, a confirmed one is final.
+const (
+	SettlementPending   = "pending"
+	SettlementConfirmed = "confirmed"
+	SettlementDisputed  = "disputed"
+)
+
+// Settlement records a contractor's answer to the trips and amount we owe
+// them for a month. TripCount and Amount are the figures they were shown
+// when they answered.
+type Settlement struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id,omitempty"`
+	ContractorID  uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_settlements_period" json:"contractor_id"`
+	Contractor    Contractor `gorm:"foreignKey:ContractorID" json:"contractor,omitempty"`
+	Year          int        `gorm:"not null;uniqueIndex:idx_settlements_period" json:"year"`
+	Month         int        `gorm:"not null;uniqueIndex:idx_settlements_period" json:"month"`
+	TripCount     int64      `gorm:"not null;default:0" json:"trip_count"`
+	Amount        float64    `gorm:"not null;default:0" json:"amount"`
+	Status        string     `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	DisputeReason string     `gorm:"type:text" json:"dispute_reason,omitempty"`
+	RespondedByID *uuid.UUID `gorm:"type:uuid" json:"responded_by_id,omitempty"`
+	RespondedAt   *time.Time `json:"responded_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+type DisputeSettlementInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
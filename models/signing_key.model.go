@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SigningKey is an RSA key pair used to sign access or refresh tokens.
+// Tokens name the key that signed them in their kid header. The newest active
+// key of each use signs new tokens, older ones only verify until retired.
+type SigningKey struct {
+	ID         string     `gorm:"type:varchar(64);primary_key" json:"id"`
+	Use        string     `gorm:"type:varchar(16);not null;index" json:"use"`
+	PrivateKey string     `gorm:"type:text" json:"-"`
+	PublicKey  string     `gorm:"type:text;not null" json:"-"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+}
@@ -17,6 +17,35 @@ type User struct {
 	Verified  bool      `gorm:"not null" json:"-"`
 	CreatedAt time.Time `gorm:"not null" json:"created_at"`
 	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+
+	FailedLoginCount  int        `gorm:"not null;default:0" json:"failed_login_count"`
+	LastFailedLoginAt *time.Time `json:"-"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty"`
+
+	TwoFactorEnabled   bool            `gorm:"not null;default:false" json:"two_factor_enabled"`
+	TOTPSecret         string          `json:"-"`
+	TOTPLastUsedStep   int64           `gorm:"not null;default:0" json:"-"`
+	RecoveryCodeHashes JSONBStringList `gorm:"type:jsonb" json:"-"`
+
+	// DriverID links a driver account to its driver record
+	DriverID *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"driver_id,omitempty"`
+	// ContractorID links a partner account to the external contractor it works for
+	ContractorID *uuid.UUID `gorm:"type:uuid;index" json:"contractor_id,omitempty"`
+}
+
+// TenantInternal is the tenant of staff accounts that work for the company itself
+const TenantInternal = "internal"
+
+// Tenant returns the data partition the user belongs to. It is put in the
+// access token so downstream services can scope requests without a lookup.
+func (u *User) Tenant() string {
+	if u.DriverID != nil {
+		return "driver:" + u.DriverID.String()
+	}
+	if u.ContractorID != nil {
+		return "contractor:" + u.ContractorID.String()
+	}
+	return TenantInternal
 }
 
 type SignUpInput struct {
@@ -43,6 +72,34 @@ type UserResponse struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type TwoFactorVerifyInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+type TwoFactorSetupInput struct {
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+type TwoFactorEnableInput struct {
+	ChallengeToken string `json:"challenge_token,omitempty"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorDisableInput struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
+
+type LinkDriverInput struct {
+	DriverID *uuid.UUID `json:"driver_id"`
+}
+
+type LinkContractorInput struct {
+	ContractorID *uuid.UUID `json:"contractor_id"`
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+type APIKeyRouteController struct {
+	apiKeyController controllers.APIKeyController
+}
+
+func NewAPIKeyRouteController(apiKeyController controllers.APIKeyController) APIKeyRouteController {
+	return APIKeyRouteController{apiKeyController}
+}
+
+func (rc *APIKeyRouteController) APIKeyRoute(rg *gin.RouterGroup) {
+	router := rg.Group("api-keys")
+	router.Use(middleware.DeserializeUser(), middleware.RequireSession(), middleware.RequirePermission(models.PermAPIKeysManage))
+
+	router.POST("", rc.apiKeyController.CreateAPIKey)
+	router.GET("", rc.apiKeyController.FindAPIKeys)
+	router.DELETE("/:apiKeyId", rc.apiKeyController.RevokeAPIKey)
+}
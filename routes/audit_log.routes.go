@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+type AuditLogRouteController struct {
+	auditLogController controllers.AuditLogController
+}
+
+func NewAuditLogRouteController(auditLogController controllers.AuditLogController) AuditLogRouteController {
+	return AuditLogRouteController{auditLogController}
+}
+
+func (rc *AuditLogRouteController) AuditLogRoute(rg *gin.RouterGroup) {
+	router := rg.Group("audit-logs")
+	router.Use(middleware.DeserializeUser(), middleware.RequirePermission(models.PermAuditRead))
+
+	router.GET("", rc.auditLogController.FindAuditLogs)
+	router.GET("/actors/:userId", rc.auditLogController.FindAuditLogs)
+	router.GET("/:entityType/:entityId", rc.auditLogController.FindAuditLogs)
+}
@@ -19,7 +19,21 @@ func (rc *AuthRouteController) AuthRoute(rg *gin.RouterGroup) {
 
 	router.POST("/register", rc.authController.SignUpUser)
 	router.POST("/login", rc.authController.SignInUser)
-	router.POST("/reset-password/:userId", middleware.DeserializeUser(), rc.authController.ResetPassword)
+	router.GET("/verify-email/:token", rc.authController.VerifyEmail)
+	router.GET("/invitations/:token", rc.authController.FindInvitation)
+	router.POST("/accept-invitation", rc.authController.AcceptInvitation)
+	router.POST("/forgot-password", rc.authController.ForgotPassword)
+	router.POST("/reset-password", rc.authController.ResetPassword)
+	router.POST("/change-password", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.ChangePassword)
 	router.POST("/refresh", rc.authController.RefreshAccessToken)
-	router.GET("/logout", middleware.DeserializeUser(), rc.authController.LogoutUser)
+	router.POST("/2fa/verify", rc.authController.VerifyTwoFactor)
+	router.POST("/2fa/enroll/setup", rc.authController.SetupTwoFactor)
+	router.POST("/2fa/enroll/enable", rc.authController.EnableTwoFactor)
+	router.POST("/2fa/setup", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.SetupTwoFactor)
+	router.POST("/2fa/enable", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.EnableTwoFactor)
+	router.POST("/2fa/disable", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.DisableTwoFactor)
+	router.POST("/2fa/recovery-codes", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.RegenerateRecoveryCodes)
+	router.GET("/logout", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.LogoutUser)
+	router.GET("/sessions", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.FindSessions)
+	router.DELETE("/sessions/:sessionId", middleware.DeserializeUser(), middleware.RequireSession(), rc.authController.RevokeSession)
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type ClientRouteController struct {
@@ -18,8 +19,8 @@ func (rc *ClientRouteController) ClientRoute(rg *gin.RouterGroup) {
 	router := rg.Group("clients")
 	router.Use(middleware.DeserializeUser())
 
-	router.POST("", rc.ClientController.CreateClient)
-	router.GET("", rc.ClientController.FindClients)
-	router.PUT("/:clientId", rc.ClientController.UpdateClient)
-	router.DELETE("/:clientId", rc.ClientController.DeleteClient)
+	router.POST("", middleware.RequirePermission(models.PermClientsWrite), rc.ClientController.CreateClient)
+	router.GET("", middleware.RequirePermission(models.PermClientsRead), rc.ClientController.FindClients)
+	router.PUT("/:clientId", middleware.RequirePermission(models.PermClientsWrite), rc.ClientController.UpdateClient)
+	router.DELETE("/:clientId", middleware.RequirePermission(models.PermClientsWrite), rc.ClientController.DeleteClient)
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type ContractorRouteController struct {
@@ -18,8 +19,8 @@ func (rc *ContractorRouteController) ContractorRoute(rg *gin.RouterGroup) {
 	router := rg.Group("contractors")
 	router.Use(middleware.DeserializeUser())
 
-	router.POST("", rc.contractorController.CreateContractor)
-	router.GET("", rc.contractorController.FindContractors)
-	router.PUT("/:contractorId", rc.contractorController.UpdateContractor)
-	router.DELETE("/:contractorId", rc.contractorController.DeleteContractor)
+	router.POST("", middleware.RequirePermission(models.PermContractorsWrite), rc.contractorController.CreateContractor)
+	router.GET("", middleware.RequirePermission(models.PermContractorsRead), rc.contractorController.FindContractors)
+	router.PUT("/:contractorId", middleware.RequirePermission(models.PermContractorsWrite), rc.contractorController.UpdateContractor)
+	router.DELETE("/:contractorId", middleware.RequirePermission(models.PermContractorsWrite), rc.contractorController.DeleteContractor)
 }
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+)
+
+type ContractorPortalRouteController struct {
+	contractorPortalController controllers.ContractorPortalController
+}
+
+func NewContractorPortalRouteController(contractorPortalController controllers.ContractorPortalController) ContractorPortalRouteController {
+	return ContractorPortalRouteController{contractorPortalController}
+}
+
+func (rc *ContractorPortalRouteController) ContractorPortalRoute(rg *gin.RouterGroup) {
+	router := rg.Group("me/contractor")
+	router.Use(middleware.DeserializeUser(), middleware.RequireContractor())
+
+	router.GET("", rc.contractorPortalController.GetProfile)
+	router.GET("/orders", rc.contractorPortalController.GetOrders)
+	router.GET("/orders/:orderId", rc.contractorPortalController.GetOrder)
+	router.GET("/pricings", rc.contractorPortalController.GetPricings)
+	router.GET("/trucks", rc.contractorPortalController.GetTrucks)
+	router.GET("/drivers", rc.contractorPortalController.GetDrivers)
+	router.GET("/payslips", rc.contractorPortalController.GetPayslips)
+	router.GET("/payslips/:payslipId", rc.contractorPortalController.GetPayslip)
+	router.GET("/settlements", rc.contractorPortalController.GetSettlements)
+	router.GET("/settlements/:year/:month", rc.contractorPortalController.GetSettlement)
+	router.POST("/settlements/:year/:month/confirm", rc.contractorPortalController.ConfirmSettlement)
+	router.POST("/settlements/:year/:month/dispute", rc.contractorPortalController.DisputeSettlement)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type DriverRouteController struct {
@@ -18,11 +19,11 @@ func (rc *DriverRouteController) DriverRoute(rg *gin.RouterGroup) {
 	router := rg.Group("drivers")
 	router.Use(middleware.DeserializeUser())
 
-	router.POST("", rc.driverController.CreateDriver)
-	router.GET("", rc.driverController.FindDrivers)
-	router.POST("/:driverId", rc.driverController.UpdateDriver)
-	router.GET("/:driverId", rc.driverController.FindDriverById)
-	router.PUT("/:driverId", rc.driverController.UpdateDriver)
-	router.DELETE("/:driverId", rc.driverController.DeleteDriver)
-	router.POST("/delete", rc.driverController.DeleteDrivers)
+	router.POST("", middleware.RequirePermission(models.PermDriversWrite), rc.driverController.CreateDriver)
+	router.GET("", middleware.RequirePermission(models.PermDriversRead), rc.driverController.FindDrivers)
+	router.POST("/:driverId", middleware.RequirePermission(models.PermDriversWrite), rc.driverController.UpdateDriver)
+	router.GET("/:driverId", middleware.RequirePermission(models.PermDriversRead), rc.driverController.FindDriverById)
+	router.PUT("/:driverId", middleware.RequirePermission(models.PermDriversWrite), rc.driverController.UpdateDriver)
+	router.DELETE("/:driverId", middleware.RequirePermission(models.PermDriversWrite), rc.driverController.DeleteDriver)
+	router.POST("/delete", middleware.RequirePermission(models.PermDriversWrite), rc.driverController.DeleteDrivers)
 }
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+)
+
+type DriverPortalRouteController struct {
+	driverPortalController controllers.DriverPortalController
+}
+
+func NewDriverPortalRouteController(driverPortalController controllers.DriverPortalController) DriverPortalRouteController {
+	return DriverPortalRouteController{driverPortalController}
+}
+
+func (rc *DriverPortalRouteController) DriverPortalRoute(rg *gin.RouterGroup) {
+	router := rg.Group("me/driver")
+	router.Use(middleware.DeserializeUser(), middleware.RequireDriver())
+
+	router.GET("", rc.driverPortalController.GetProfile)
+	router.GET("/orders", rc.driverPortalController.GetOrders)
+	router.GET("/orders/:orderId", rc.driverPortalController.GetOrder)
+	router.GET("/payslips", rc.driverPortalController.GetPayslips)
+	router.GET("/payslips/:payslipId", rc.driverPortalController.GetPayslip)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type FileRouteController struct {
@@ -18,8 +19,8 @@ func (rc *FileRouteController) FileRoute(rg *gin.RouterGroup) {
 	router := rg.Group("files")
 	router.Use(middleware.DeserializeUser())
 
-	router.POST("/upload", rc.fileController.UploadFile)
-	router.GET("/download/:fileName", rc.fileController.DownloadFile)
-	router.DELETE("/delete/:fileName", rc.fileController.DeleteFile)
-	router.GET("", rc.fileController.ListFiles)
+	router.POST("/upload", middleware.RequirePermission(models.PermFilesWrite), rc.fileController.UploadFile)
+	router.GET("/download/:fileName", middleware.RequirePermission(models.PermFilesRead), rc.fileController.DownloadFile)
+	router.DELETE("/delete/:fileName", middleware.RequirePermission(models.PermFilesWrite), rc.fileController.DeleteFile)
+	router.GET("", middleware.RequirePermission(models.PermFilesRead), rc.fileController.ListFiles)
 }
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+type InvitationRouteController struct {
+	invitationController controllers.InvitationController
+}
+
+func NewInvitationRouteController(invitationController controllers.InvitationController) InvitationRouteController {
+	return InvitationRouteController{invitationController}
+}
+
+func (rc *InvitationRouteController) InvitationRoute(rg *gin.RouterGroup) {
+	router := rg.Group("invitations")
+	router.Use(middleware.DeserializeUser())
+
+	router.POST("", middleware.RequirePermission(models.PermUsersWrite), rc.invitationController.CreateInvitation)
+	router.GET("", middleware.RequirePermission(models.PermUsersRead), rc.invitationController.FindInvitations)
+	router.DELETE("/:invitationId", middleware.RequirePermission(models.PermUsersWrite), rc.invitationController.RevokeInvitation)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+)
+
+type JWKSRouteController struct {
+	jwksController controllers.JWKSController
+}
+
+func NewJWKSRouteController(jwksController controllers.JWKSController) JWKSRouteController {
+	return JWKSRouteController{jwksController}
+}
+
+// JWKSRoute is registered on the server root, not under /api, where clients
+// expect to find it
+func (rc *JWKSRouteController) JWKSRoute(rg *gin.RouterGroup) {
+	rg.GET("/.well-known/jwks.json", rc.jwksController.GetJWKS)
+}
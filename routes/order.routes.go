@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type OrderRouteController struct {
@@ -18,9 +19,17 @@ func (rc *OrderRouteController) OrderRoute(rg *gin.RouterGroup) {
 	router := rg.Group("orders")
 	router.Use(middleware.DeserializeUser())
 
-	router.POST("", rc.orderController.CreateOrder)            // Create a new order
-	router.GET("", rc.orderController.GetOrders)               // Get all orders
-	router.GET("/:orderId", rc.orderController.GetOrderByID)   // Get a specific order by ID
-	router.PUT("/:orderId", rc.orderController.UpdateOrder)    // Update an order by ID
-	router.DELETE("/:orderId", rc.orderController.DeleteOrder) // Delete an order by ID
+	router.POST("", middleware.RequirePermission(models.PermOrdersWrite), rc.orderController.CreateOrder)                      // Create a new order
+	router.GET("", middleware.RequirePermission(models.PermOrdersRead), rc.orderController.GetOrders)                          // Get all orders
+	router.GET("/export", middleware.RequirePermission(models.PermOrdersRead), rc.orderController.ExportOrders)                // Download the filtered orders as XLSX or CSV
+	router.GET("/availability", middleware.RequirePermission(models.PermOrdersRead), rc.orderController.GetAvailability)       // Get the drivers and trucks free in a time window
+	router.GET("/overloads", middleware.RequirePermission(models.PermOrdersRead), rc.orderController.GetOverloadReport)        // Get last month's orders that overloaded their truck
+	router.GET("/import/template", middleware.RequirePermission(models.PermOrdersWrite), rc.orderController.GetImportTemplate) // Download the import template
+	router.POST("/import", middleware.RequirePermission(models.PermOrdersWrite), rc.orderController.ImportOrders)              // Validate or import orders from a spreadsheet
+	router.POST("/calculate", middleware.RequirePermission(models.PermSalaryRead), rc.orderController.CalculateOrder)          // Calculate an order's total without saving it
+	router.GET("/:orderId", middleware.RequirePermission(models.PermOrdersRead), rc.orderController.GetOrderByID)              // Get a specific order by ID
+	router.PUT("/:orderId", middleware.RequirePermission(models.PermOrdersWrite), rc.orderController.UpdateOrder)              // Update an order by ID
+	router.PUT("/:orderId/status", middleware.RequirePermission(models.PermOrdersWrite), rc.orderController.UpdateOrderStatus) // Move an order to another status
+	router.GET("/:orderId/history", middleware.RequirePermission(models.PermOrdersRead), rc.orderController.GetOrderHistory)   // Get the status history of an order
+	router.DELETE("/:orderId", middleware.RequirePermission(models.PermOrdersWrite), rc.orderController.DeleteOrder)           // Delete an order by ID
 }
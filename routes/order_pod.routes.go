@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+type OrderPODRouteController struct {
+	orderPODController controllers.OrderPODController
+}
+
+func NewOrderPODRouteController(orderPODController controllers.OrderPODController) OrderPODRouteController {
+	return OrderPODRouteController{orderPODController}
+}
+
+func (rc *OrderPODRouteController) OrderPODRoute(rg *gin.RouterGroup) {
+	router := rg.Group("orders/:orderId/pods")
+	router.Use(middleware.DeserializeUser())
+
+	router.POST("", middleware.RequirePermission(models.PermOrdersWrite), rc.orderPODController.CreatePOD) // Add a proof of delivery to an order
+	router.GET("", middleware.RequirePermission(models.PermOrdersRead), rc.orderPODController.GetPODs)     // Get the proofs of delivery of an order
+
+	// Drivers add proofs of delivery to their own orders
+	driver := rg.Group("me/driver/orders/:orderId/pods")
+	driver.Use(middleware.DeserializeUser(), middleware.RequireDriver())
+
+	driver.POST("", rc.orderPODController.CreateDriverPOD)
+}
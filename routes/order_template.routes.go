@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+type OrderTemplateRouteController struct {
+	orderTemplateController controllers.OrderTemplateController
+}
+
+func NewOrderTemplateRouteController(orderTemplateController controllers.OrderTemplateController) OrderTemplateRouteController {
+	return OrderTemplateRouteController{orderTemplateController}
+}
+
+// OrderTemplateRoute serves the recurring order templates and the holidays
+// their scheduler skips
+func (rc *OrderTemplateRouteController) OrderTemplateRoute(rg *gin.RouterGroup) {
+	router := rg.Group("order-templates")
+	router.Use(middleware.DeserializeUser())
+
+	router.POST("", middleware.RequirePermission(models.PermOrdersWrite), rc.orderTemplateController.CreateTemplate)                  // Create a new order template
+	router.GET("", middleware.RequirePermission(models.PermOrdersRead), rc.orderTemplateController.GetTemplates)                      // Get all order templates
+	router.GET("/runs", middleware.RequirePermission(models.PermOrdersRead), rc.orderTemplateController.GetTemplateRuns)              // Get the log of the days the scheduler booked
+	router.POST("/generate", middleware.RequirePermission(models.PermOrdersWrite), rc.orderTemplateController.GenerateTemplateOrders) // Book the template orders now
+	router.GET("/:templateId", middleware.RequirePermission(models.PermOrdersRead), rc.orderTemplateController.GetTemplate)           // Get a specific order template by ID
+	router.PUT("/:templateId", middleware.RequirePermission(models.PermOrdersWrite), rc.orderTemplateController.UpdateTemplate)       // Update an order template by ID
+	router.DELETE("/:templateId", middleware.RequirePermission(models.PermOrdersWrite), rc.orderTemplateController.DeleteTemplate)    // Delete an order template by ID
+
+	holidays := rg.Group("holidays")
+	holidays.Use(middleware.DeserializeUser())
+
+	holidays.GET("", middleware.RequirePermission(models.PermSettingsRead), rc.orderTemplateController.GetHolidays)                  // Get the holidays, of one year with year
+	holidays.POST("", middleware.RequirePermission(models.PermSettingsWrite), rc.orderTemplateController.CreateHoliday)              // Add a holiday
+	holidays.DELETE("/:holidayId", middleware.RequirePermission(models.PermSettingsWrite), rc.orderTemplateController.DeleteHoliday) // Remove a holiday
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type PayslipRouteController struct {
@@ -18,9 +19,9 @@ func (rc *PayslipRouteController) PayslipRoute(rg *gin.RouterGroup) {
 	router := rg.Group("payslips")
 	router.Use(middleware.DeserializeUser())
 
-	router.POST("", rc.payslipController.CreatePayslip)              // Create a new payslip
-	router.GET("", rc.payslipController.GetPayslips)                 // Get all payslips
-	router.GET("/:payslipId", rc.payslipController.GetPayslipByID)   // Get a specific payslip by ID
-	router.PUT("/:payslipId", rc.payslipController.UpdatePayslip)    // Update a payslip by ID
-	router.DELETE("/:payslipId", rc.payslipController.DeletePayslip) // Delete a payslip by ID
+	router.POST("", middleware.RequirePermission(models.PermPayslipsWrite), rc.payslipController.CreatePayslip)              // Create a new payslip
+	router.GET("", middleware.RequirePermission(models.PermPayslipsRead), rc.payslipController.GetPayslips)                  // Get all payslips
+	router.GET("/:payslipId", middleware.RequirePermission(models.PermPayslipsRead), rc.payslipController.GetPayslipByID)    // Get a specific payslip by ID
+	router.PUT("/:payslipId", middleware.RequirePermission(models.PermPayslipsWrite), rc.payslipController.UpdatePayslip)    // Update a payslip by ID
+	router.DELETE("/:payslipId", middleware.RequirePermission(models.PermPayslipsWrite), rc.payslipController.DeletePayslip) // Delete a payslip by ID
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type PostRouteController struct {
@@ -18,9 +19,9 @@ func (pc *PostRouteController) PostRoute(rg *gin.RouterGroup) {
 
 	router := rg.Group("posts")
 	router.Use(middleware.DeserializeUser())
-	router.POST("/", pc.postController.CreatePost)
-	router.GET("/", pc.postController.FindPosts)
-	router.PUT("/:postId", pc.postController.UpdatePost)
-	router.GET("/:postId", pc.postController.FindPostById)
-	router.DELETE("/:postId", pc.postController.DeletePost)
+	router.POST("/", middleware.RequirePermission(models.PermPostsWrite), pc.postController.CreatePost)
+	router.GET("/", middleware.RequirePermission(models.PermPostsRead), pc.postController.FindPosts)
+	router.PUT("/:postId", middleware.RequirePermission(models.PermPostsWrite), pc.postController.UpdatePost)
+	router.GET("/:postId", middleware.RequirePermission(models.PermPostsRead), pc.postController.FindPostById)
+	router.DELETE("/:postId", middleware.RequirePermission(models.PermPostsWrite), pc.postController.DeletePost)
 }
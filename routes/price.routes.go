@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type PricingRouteController struct {
@@ -19,15 +20,17 @@ func (rc *PricingRouteController) PricingRoute(rg *gin.RouterGroup) {
 	router.Use(middleware.DeserializeUser())
 
 	// Route to create new pricing
-	router.POST("/:ownerId", rc.pricingController.CreatePricing)
+	router.POST("/:ownerId", middleware.RequirePermission(models.PermPricingWrite), rc.pricingController.CreatePricing)
 
-	router.GET("/:ownerId", rc.pricingController.FindPricingListByOwner)
+	router.GET("/:ownerId", middleware.RequirePermission(models.PermPricingRead), rc.pricingController.FindPricingListByOwner)
 
-	router.GET("/:ownerId/latest", rc.pricingController.FindLatestPricingByOwner)
+	router.GET("/:ownerId/latest", middleware.RequirePermission(models.PermPricingRead), rc.pricingController.FindLatestPricingByOwner)
 
-	router.GET("/:ownerId/:priceId", rc.pricingController.FindPricingByOwnerAndPriceID)
+	router.GET("/:ownerId/:priceId", middleware.RequirePermission(models.PermPricingRead), rc.pricingController.FindPricingByOwnerAndPriceID)
 
-	router.DELETE("/:ownerId/:priceId", rc.pricingController.DeletePricingWithDetails)
+	router.PUT("/:ownerId/:priceId", middleware.RequirePermission(models.PermPricingWrite), rc.pricingController.UpdatePricing)
 
-	router.DELETE("/:ownerId", rc.pricingController.DeleteAllPricingByContractorID)
+	router.DELETE("/:ownerId/:priceId", middleware.RequirePermission(models.PermPricingWrite), rc.pricingController.DeletePricingWithDetails)
+
+	router.DELETE("/:ownerId", middleware.RequirePermission(models.PermPricingWrite), rc.pricingController.DeleteAllPricingByContractorID)
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type SettingRouteController struct {
@@ -17,6 +18,6 @@ func NewSettingRouteController(settingController controllers.SettingController)
 func (sc *SettingRouteController) SettingRoute(rg *gin.RouterGroup) {
 	router := rg.Group("settings")
 	router.Use(middleware.DeserializeUser())
-	router.POST("", sc.settingController.UpdateSetting)
-	router.GET("", sc.settingController.GetSetting)
+	router.POST("", middleware.RequirePermission(models.PermSettingsWrite), sc.settingController.UpdateSetting)
+	router.GET("", middleware.RequirePermission(models.PermSettingsRead), sc.settingController.GetSetting)
 }
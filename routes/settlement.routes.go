@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
+)
+
+type SettlementRouteController struct {
+	settlementController controllers.SettlementController
+}
+
+func NewSettlementRouteController(settlementController controllers.SettlementController) SettlementRouteController {
+	return SettlementRouteController{settlementController}
+}
+
+func (rc *SettlementRouteController) SettlementRoute(rg *gin.RouterGroup) {
+	router := rg.Group("settlements")
+	router.Use(middleware.DeserializeUser())
+
+	router.GET("", middleware.RequirePermission(models.PermPayslipsRead), rc.settlementController.FindSettlements)
+}
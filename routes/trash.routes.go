@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wpcodevo/golang-gorm-postgres/controllers"
+	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+)
+
+type TrashRouteController struct {
+	trashController controllers.TrashController
+}
+
+func NewTrashRouteController(trashController controllers.TrashController) TrashRouteController {
+	return TrashRouteController{trashController}
+}
+
+// TrashRoute serves the trash of orders, payslips, drivers, trucks,
+// contractors and clients. Permissions depend on the type and are checked
+// by the handlers.
+func (rc *TrashRouteController) TrashRoute(rg *gin.RouterGroup) {
+	router := rg.Group("trash")
+	router.Use(middleware.DeserializeUser())
+
+	router.GET("/:type", rc.trashController.GetTrash)                  // List the deleted records of a type
+	router.POST("/:type/:id/restore", rc.trashController.RestoreTrash) // Restore a deleted record
+	router.DELETE("/:type/:id", rc.trashController.PurgeTrash)         // Permanently delete a record after the retention period
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type TruckRouteController struct {
@@ -18,10 +19,10 @@ func (rc *TruckRouteController) TruckRoute(rg *gin.RouterGroup) {
 	router := rg.Group("trucks")
 	router.Use(middleware.DeserializeUser())
 
-	router.POST("", rc.truckController.CreateTruck)
-	router.GET("", rc.truckController.FindTrucks)
-	router.PUT("/:truckId", rc.truckController.UpdateTruck)
-	router.GET("/:truckId", rc.truckController.FindTruckById)
-	router.DELETE("/:truckId", rc.truckController.DeleteTruck)
-	router.POST("/delete", rc.truckController.DeleteTrucks)
+	router.POST("", middleware.RequirePermission(models.PermTrucksWrite), rc.truckController.CreateTruck)
+	router.GET("", middleware.RequirePermission(models.PermTrucksRead), rc.truckController.FindTrucks)
+	router.PUT("/:truckId", middleware.RequirePermission(models.PermTrucksWrite), rc.truckController.UpdateTruck)
+	router.GET("/:truckId", middleware.RequirePermission(models.PermTrucksRead), rc.truckController.FindTruckById)
+	router.DELETE("/:truckId", middleware.RequirePermission(models.PermTrucksWrite), rc.truckController.DeleteTruck)
+	router.POST("/delete", middleware.RequirePermission(models.PermTrucksWrite), rc.truckController.DeleteTrucks)
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wpcodevo/golang-gorm-postgres/controllers"
 	"github.com/wpcodevo/golang-gorm-postgres/middleware"
+	"github.com/wpcodevo/golang-gorm-postgres/models"
 )
 
 type UserRouteController struct {
@@ -20,8 +21,19 @@ func (uc *UserRouteController) UserRoute(rg *gin.RouterGroup) {
 	router.Use(middleware.DeserializeUser())
 
 	router.GET("/me", uc.userController.GetMe)
-	router.GET("", uc.userController.FindUsers)
-	router.GET("/:userId", uc.userController.FindUser)
-	router.PUT("/:userId", uc.userController.UpdateUser)
-	router.DELETE("/:userId", uc.userController.DeleteUser)
+	router.GET("/roles", middleware.RequirePermission(models.PermUsersRead), uc.userController.FindRoles)
+	router.PUT("/roles/:role/policy", middleware.RequirePermission(models.PermUsersWrite), uc.userController.UpdateRolePolicy)
+	router.GET("/login-history", middleware.RequirePermission(models.PermUsersRead), uc.userController.FindLoginHistory)
+	router.GET("", middleware.RequirePermission(models.PermUsersRead), uc.userController.FindUsers)
+	router.GET("/:userId", middleware.RequirePermission(models.PermUsersRead), uc.userController.FindUser)
+	router.PUT("/:userId", middleware.RequirePermission(models.PermUsersWrite), uc.userController.UpdateUser)
+	router.PUT("/:userId/role", middleware.RequirePermission(models.PermUsersWrite), uc.userController.UpdateUserRole)
+	router.PUT("/:userId/driver", middleware.RequirePermission(models.PermUsersWrite), uc.userController.LinkDriver)
+	router.PUT("/:userId/contractor", middleware.RequirePermission(models.PermUsersWrite), uc.userController.LinkContractor)
+	router.GET("/:userId/login-history", middleware.RequirePermission(models.PermUsersRead), uc.userController.FindLoginHistory)
+	router.POST("/:userId/unlock", middleware.RequirePermission(models.PermUsersWrite), uc.userController.UnlockUser)
+	router.POST("/:userId/2fa/reset", middleware.RequirePermission(models.PermUsersWrite), uc.userController.ResetUserTwoFactor)
+	router.POST("/:userId/reset-password", middleware.RequirePermission(models.PermUsersWrite), uc.userController.ResetUserPassword)
+	router.DELETE("/:userId/sessions", middleware.RequirePermission(models.PermUsersWrite), uc.userController.RevokeUserSessions)
+	router.DELETE("/:userId", middleware.RequirePermission(models.PermUsersWrite), uc.userController.DeleteUser)
 }
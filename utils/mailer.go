@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer delivers plain text emails
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// SMTPMailer sends emails through an SMTP server. Authentication is skipped
+// when no username is set, which is what local SMTP catchers expect.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	message := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"UTF-8\"",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(message)); err != nil {
+		return fmt.Errorf("could not send email: %w", err)
+	}
+	return nil
+}
+
+// LogMailer records emails in the server log instead of sending them. Only
+// the recipient and subject are logged, bodies carry tokens and links.
+type LogMailer struct{}
+
+func (m *LogMailer) Send(to string, subject string, body string) error {
+	log.Printf("📧 Email to %s: %s (not sent, no SMTP host configured)", to, subject)
+	return nil
+}
+
+// ErrMailDisabled is returned by a DisabledMailer
+var ErrMailDisabled = errors.New("email is not configured on this server")
+
+// DisabledMailer refuses every email. It is used outside of development
+// when no SMTP host is configured, the flows that depend on email are turned
+// off instead of leaving users waiting for emails that never come.
+type DisabledMailer struct{}
+
+func (m *DisabledMailer) Send(to string, subject string, body string) error {
+	return ErrMailDisabled
+}
+
+// MailEnabled reports whether the mailer can deliver emails
+func MailEnabled(m Mailer) bool {
+	_, disabled := m.(*DisabledMailer)
+	return !disabled
+}
+
+// NewMailer returns an SMTP mailer, or a log mailer when no SMTP host is
+// configured. The log mailer is meant for development only.
+func NewMailer(host string, port string, username string, password string, from string) Mailer {
+	if host == "" {
+		return &LogMailer{}
+	}
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from}
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QueryFilter adds the conditions of a list endpoint to a query from query
+// string values. Empty values and "all" add no condition. The first invalid
+// value stops the filter, Query returns it as an error meant for the client.
+type QueryFilter struct {
+	db  *gorm.DB
+	err error
+}
+
+func NewQueryFilter(db *gorm.DB) *QueryFilter {
+	return &QueryFilter{db: db}
+}
+
+func isUnset(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || value == "all"
+}
+
+// Where adds a condition that does not come from a query string value
+func (f *QueryFilter) Where(query interface{}, args ...interface{}) *QueryFilter {
+	if f.err == nil {
+		f.db = f.db.Where(query, args...)
+	}
+	return f
+}
+
+// Equals matches the column exactly
+func (f *QueryFilter) Equals(column string, value string) *QueryFilter {
+	if f.err != nil || isUnset(value) {
+		return f
+	}
+	f.db = f.db.Where(column+" = ?", strings.TrimSpace(value))
+	return f
+}
+
+// EqualsFold matches the column ignoring case and surrounding spaces
+func (f *QueryFilter) EqualsFold(column string, value string) *QueryFilter {
+	if f.err != nil || isUnset(value) {
+		return f
+	}
+	f.db = f.db.Where("LOWER(TRIM("+column+")) = LOWER(?)", strings.TrimSpace(value))
+	return f
+}
+
+// UUID matches a uuid column, name is the parameter named in the error
+func (f *QueryFilter) UUID(column string, value string, name string) *QueryFilter {
+	if f.err != nil || isUnset(value) {
+		return f
+	}
+	id, err := uuid.Parse(strings.TrimSpace(value))
+	if err != nil {
+		f.err = fmt.Errorf("invalid %s", name)
+		return f
+	}
+	f.db = f.db.Where(column+" = ?", id)
+	return f
+}
+
+// OneOf matches any of the comma separated values, each must be valid
+func (f *QueryFilter) OneOf(column string, value string, name string, valid func(string) bool) *QueryFilter {
+	if f.err != nil || isUnset(value) {
+		return f
+	}
+	values := strings.Split(value, ",")
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+		if valid != nil && !valid(values[i]) {
+			f.err = fmt.Errorf("invalid %s %q", name, values[i])
+			return f
+		}
+	}
+	f.db = f.db.Where(column+" IN ?", values)
+	return f
+}
+
+// TimeRange keeps rows from the start of from up to the end of to. Both are
+// RFC 3339 times or 2006-01-02 dates, a date as to includes the whole day.
+func (f *QueryFilter) TimeRange(column string, from string, to string) *QueryFilter {
+	if f.err != nil {
+		return f
+	}
+	if !isUnset(from) {
+		start, _, err := parseTimeBound(from)
+		if err != nil {
+			f.err = fmt.Errorf("invalid from, use YYYY-MM-DD or RFC 3339")
+			return f
+		}
+		f.db = f.db.Where(column+" >= ?", start)
+	}
+	if !isUnset(to) {
+		end, isDate, err := parseTimeBound(to)
+		if err != nil {
+			f.err = fmt.Errorf("invalid to, use YYYY-MM-DD or RFC 3339")
+			return f
+		}
+		if isDate {
+			f.db = f.db.Where(column+" < ?", end.AddDate(0, 0, 1))
+		} else {
+			f.db = f.db.Where(column+" <= ?", end)
+		}
+	}
+	return f
+}
+
+// ParseTimeWindow reads a required from and to pair as TimeRange does and
+// returns the window they cover, a date as to includes the whole day
+func ParseTimeWindow(from string, to string) (time.Time, time.Time, error) {
+	start, _, err := parseTimeBound(from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from, use YYYY-MM-DD or RFC 3339")
+	}
+	end, isDate, err := parseTimeBound(to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to, use YYYY-MM-DD or RFC 3339")
+	}
+	if isDate {
+		end = end.AddDate(0, 0, 1)
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be after from")
+	}
+	return start, end, nil
+}
+
+func parseTimeBound(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	return parsed, false, err
+}
+
+// Search keeps rows where any of the columns contains the text, ignoring case
+func (f *QueryFilter) Search(value string, columns ...string) *QueryFilter {
+	value = strings.TrimSpace(value)
+	if f.err != nil || value == "" || len(columns) == 0 {
+		return f
+	}
+
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " ILIKE ?"
+		args[i] = pattern
+	}
+	f.db = f.db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	return f
+}
+
+// Query returns the filtered query, or the first invalid value
+func (f *QueryFilter) Query() (*gorm.DB, error) {
+	return f.db, f.err
+}
+
+// Page is the page of a list endpoint
+type Page struct {
+	Page   int `json:"page"`
+	Limit  int `json:"limit"`
+	Offset int `json:"-"`
+}
+
+// ParsePage reads the page and limit query values. A missing limit is
+// defaultLimit, a larger one than maxLimit is cut to it.
+func ParsePage(page string, limit string, defaultLimit int, maxLimit int) (Page, error) {
+	result := Page{Page: 1, Limit: defaultLimit}
+	if page != "" {
+		value, err := strconv.Atoi(page)
+		if err != nil || value < 1 {
+			return result, fmt.Errorf("invalid page")
+		}
+		result.Page = value
+	}
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return result, fmt.Errorf("invalid limit")
+		}
+		result.Limit = value
+	}
+	if result.Limit > maxLimit {
+		result.Limit = maxLimit
+	}
+	result.Offset = (result.Page - 1) * result.Limit
+	return result, nil
+}
+
+// ParseSort turns a sort query value such as "-order_time,trip_count" into
+// an ORDER BY clause. A leading "-" sorts descending. Only the fields in
+// allowed, which maps them to their column, can be used. The tieBreaker
+// column is appended so pages do not overlap.
+func ParseSort(value string, allowed map[string]string, fallback string, tieBreaker string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		value = fallback
+	}
+
+	var order []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			field, direction = field[1:], "DESC"
+		}
+		column, ok := allowed[field]
+		if !ok {
+			return "", fmt.Errorf("cannot sort by %q", field)
+		}
+		order = append(order, column+" "+direction)
+	}
+	if tieBreaker != "" {
+		order = append(order, tieBreaker)
+	}
+	return strings.Join(order, ", "), nil
+}
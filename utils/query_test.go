@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type queryTestRow struct {
+	ID     string
+	Status string
+}
+
+// dryRunDB builds SQL without a database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestQueryFilter(t *testing.T) {
+	validStatus := func(value string) bool { return value == "draft" || value == "delivered" }
+
+	tests := []struct {
+		name   string
+		filter func(*QueryFilter) *QueryFilter
+		want   []string
+		err    string
+	}{
+		{
+			name: "unset values add no condition",
+			filter: func(f *QueryFilter) *QueryFilter {
+				return f.Equals("status", "").Equals("unit", "all").Search("  ", "notes")
+			},
+			want: []string{`SELECT * FROM "query_test_rows"`},
+		},
+		{
+			name:   "equals",
+			filter: func(f *QueryFilter) *QueryFilter { return f.Equals("status", " draft ") },
+			want:   []string{"WHERE status = 'draft'"},
+		},
+		{
+			name:   "equals ignoring case",
+			filter: func(f *QueryFilter) *QueryFilter { return f.EqualsFold("pickup_province", "Hà Nội") },
+			want:   []string{"LOWER(TRIM(pickup_province)) = LOWER('Hà Nội')"},
+		},
+		{
+			name: "uuid",
+			filter: func(f *QueryFilter) *QueryFilter {
+				return f.UUID("driver_id", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "driver_id")
+			},
+			want: []string{"driver_id = '6ba7b810-9dad-11d1-80b4-00c04fd430c8'"},
+		},
+		{
+			name:   "invalid uuid",
+			filter: func(f *QueryFilter) *QueryFilter { return f.UUID("driver_id", "42", "driver_id") },
+			err:    "invalid driver_id",
+		},
+		{
+			name:   "one of",
+			filter: func(f *QueryFilter) *QueryFilter { return f.OneOf("status", "draft, delivered", "status", validStatus) },
+			want:   []string{"status IN ('draft','delivered')"},
+		},
+		{
+			name:   "one of with an invalid value",
+			filter: func(f *QueryFilter) *QueryFilter { return f.OneOf("status", "draft,lost", "status", validStatus) },
+			err:    `invalid status "lost"`,
+		},
+		{
+			name:   "date range includes the whole last day",
+			filter: func(f *QueryFilter) *QueryFilter { return f.TimeRange("order_time", "2024-03-01", "2024-03-31") },
+			want:   []string{"order_time >= '2024-03-01 00:00:00", "order_time < '2024-04-01 00:00:00"},
+		},
+		{
+			name:   "time range",
+			filter: func(f *QueryFilter) *QueryFilter { return f.TimeRange("order_time", "", "2024-03-31T12:00:00Z") },
+			want:   []string{"order_time <= '2024-03-31 12:00:00"},
+		},
+		{
+			name:   "invalid time",
+			filter: func(f *QueryFilter) *QueryFilter { return f.TimeRange("order_time", "01/03/2024", "") },
+			err:    "invalid from, use YYYY-MM-DD or RFC 3339",
+		},
+		{
+			name:   "search escapes wildcards",
+			filter: func(f *QueryFilter) *QueryFilter { return f.Search("50%_off", "notes", "unit") },
+			want:   []string{`(notes ILIKE '%50\%\_off%' OR unit ILIKE '%50\%\_off%')`},
+		},
+		{
+			name: "the first error stops the filter",
+			filter: func(f *QueryFilter) *QueryFilter {
+				return f.UUID("client_id", "x", "client_id").OneOf("status", "lost", "status", validStatus)
+			},
+			err: "invalid client_id",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := test.filter(NewQueryFilter(dryRunDB(t).Model(&queryTestRow{}))).Query()
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sql := query.ToSQL(func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]queryTestRow{}) })
+			for _, want := range test.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("%s does not contain %s", sql, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		page  string
+		limit string
+		want  Page
+		err   string
+	}{
+		{"", "", Page{Page: 1, Limit: 20, Offset: 0}, ""},
+		{"3", "", Page{Page: 3, Limit: 20, Offset: 40}, ""},
+		{"2", "50", Page{Page: 2, Limit: 50, Offset: 50}, ""},
+		{"1", "500", Page{Page: 1, Limit: 100, Offset: 0}, ""},
+		{"0", "", Page{}, "invalid page"},
+		{"-1", "", Page{}, "invalid page"},
+		{"two", "", Page{}, "invalid page"},
+		{"1", "0", Page{}, "invalid limit"},
+		{"1", "-10", Page{}, "invalid limit"},
+	}
+
+	for _, test := range tests {
+		got, err := ParsePage(test.page, test.limit, 20, 100)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("ParsePage(%q, %q): got error %v, want %q", test.page, test.limit, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParsePage(%q, %q) = %+v, %v, want %+v", test.page, test.limit, got, err, test.want)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	allowed := map[string]string{"order_time": "order_time", "client": "client_name", "trip_count": "trip_count"}
+
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{"", "order_time DESC, id", ""},
+		{"order_time", "order_time ASC, id", ""},
+		{"-client, trip_count", "client_name DESC, trip_count ASC, id", ""},
+		{"total_salary", "", `cannot sort by "total_salary"`},
+		{"order_time; DROP TABLE orders", "", `cannot sort by "order_time; DROP TABLE orders"`},
+		{"order_time,", "", `cannot sort by ""`},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSort(test.value, allowed, "-order_time", "id")
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("ParseSort(%q): got error %v, want %q", test.value, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParseSort(%q) = %q, %v, want %q", test.value, got, err, test.want)
+		}
+	}
+}
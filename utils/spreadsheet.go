@@ -0,0 +1,542 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ReadSpreadsheet returns the rows of a CSV file or of the first sheet of an
+// XLSX workbook, picked by the file name extension. Dates in XLSX files are
+// returned as the serial number Excel stores them as, see ParseSpreadsheetTime.
+func ReadSpreadsheet(filename string, data []byte) ([][]string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return readCSV(data)
+	case ".xlsx":
+		return readXLSX(data)
+	}
+	return nil, fmt.Errorf("unsupported file type, upload a .xlsx or .csv file")
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	// Excel writes a byte order mark in front of UTF-8 CSV files
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("the CSV file is not UTF-8 encoded")
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	// Excel uses ";" where the comma is the decimal separator
+	if firstLine := strings.SplitN(string(data), "\n", 2)[0]; strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the CSV file: %w", err)
+	}
+	return rows, nil
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelationID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("the file is not a valid XLSX workbook")
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(file, &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := decodeZipXML(files[sheetPath], &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		index := row.Index
+		if index == 0 {
+			index = i + 1
+		}
+		// Empty rows are left out of the sheet, keep the row numbers right
+		for len(rows) < index-1 {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for j, cell := range row.Cells {
+			column := j
+			if cell.Ref != "" {
+				column = xlsxColumnIndex(cell.Ref)
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				item, err := strconv.Atoi(cell.Value)
+				if err != nil || item < 0 || item >= len(sharedStrings.Items) {
+					return nil, fmt.Errorf("cell %s refers to a missing shared string", cell.Ref)
+				}
+				values[column] = sharedStrings.Items[item].String()
+			case "inlineStr":
+				values[column] = cell.Inline.String()
+			default:
+				values[column] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// firstSheetPath follows the workbook relationships to the first sheet
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	var relationships xlsxRelationships
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil {
+		return "", err
+	}
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &relationships); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("the workbook has no sheets")
+	}
+
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID != workbook.Sheets[0].RelationID {
+			continue
+		}
+		target := relationship.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		if _, ok := files[target]; !ok {
+			break
+		}
+		return target, nil
+	}
+	return "", fmt.Errorf("the first sheet of the workbook is missing")
+}
+
+func decodeZipXML(file *zip.File, value interface{}) error {
+	if file == nil {
+		return fmt.Errorf("the file is not a valid XLSX workbook")
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(reader, 64<<20)).Decode(value); err != nil {
+		return fmt.Errorf("could not read %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// xlsxColumnIndex turns the column letters of a cell reference such as
+// "AB12" into a zero based index
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
+
+// ParseSpreadsheetTime reads a date or time cell: an Excel serial number, an
+// RFC 3339 time, or a date written as 2006-01-02, 02/01/2006 or
+// 02/01/2006 15:04, day first as in Vietnam
+func ParseSpreadsheetTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		// Excel counts days from 1899-12-30, the fraction is the time of day
+		days := int(serial)
+		seconds := int((serial-float64(days))*86400 + 0.5)
+		return time.Date(1899, 12, 30, 0, 0, seconds, 0, time.Local).AddDate(0, 0, days), nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02", "02/01/2006 15:04", "2/1/2006 15:04", "02/01/2006", "2/1/2006"} {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, use DD/MM/YYYY or YYYY-MM-DD", value)
+}
+
+// spreadsheetNumberNoise removes spaces and currency signs around numbers
+var spreadsheetNumberNoise = strings.NewReplacer(" ", "", "\u00a0", "", "₫", "", "đ", "", "VND", "", "vnd", "")
+
+// ParseSpreadsheetNumber reads a number cell. Numbers are often typed with
+// thousands separators, "1.250.000" and "1,250,000" both read as 1250000, and
+// a single comma is read as the decimal separator.
+func ParseSpreadsheetNumber(value string) (float64, error) {
+	cleaned := spreadsheetNumberNoise.Replace(strings.TrimSpace(value))
+	if cleaned == "" {
+		return 0, nil
+	}
+
+	dots, commas := strings.Count(cleaned, "."), strings.Count(cleaned, ",")
+	switch {
+	case dots > 1 || (dots == 1 && commas == 1 && strings.Index(cleaned, ".") < strings.Index(cleaned, ",")):
+		// 1.250.000 or 1.250,5
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+		cleaned = strings.ReplaceAll(cleaned, ",", ".")
+	case commas > 1 || (dots == 1 && commas == 1):
+		// 1,250,000 or 1,250.5
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	case commas == 1:
+		cleaned = strings.ReplaceAll(cleaned, ",", ".")
+	}
+
+	number, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return number, nil
+}
+
+// ParseSpreadsheetAmount reads a VND amount. VND has no decimals, so a "."
+// or "," followed by groups of three digits is a thousands separator, even a
+// single one: "250.000" reads as 250000.
+func ParseSpreadsheetAmount(value string) (float64, error) {
+	cleaned := spreadsheetNumberNoise.Replace(strings.TrimSpace(value))
+	groups := strings.FieldsFunc(cleaned, func(r rune) bool { return r == '.' || r == ',' })
+	thousands := len(groups) > 1 && len(groups[0]) <= 3
+	for i := 1; thousands && i < len(groups); i++ {
+		thousands = len(groups[i]) == 3
+	}
+	if thousands {
+		cleaned = strings.Join(groups, "")
+	}
+	return ParseSpreadsheetNumber(cleaned)
+}
+
+// Spreadsheet column formats
+const (
+	SheetFormatText   = ""
+	SheetFormatVND    = "vnd"
+	SheetFormatNumber = "number"
+	SheetFormatDate   = "date"
+)
+
+// SheetColumn is a column written by WriteXLSX and WriteCSV
+type SheetColumn struct {
+	Title  string
+	Format string
+	Width  float64
+}
+
+// SheetRow is a row written by WriteXLSX and WriteCSV. Cells hold a string,
+// a number, a time.Time or nil. Bold rows are used for totals.
+type SheetRow struct {
+	Cells []interface{}
+	Bold  bool
+}
+
+// xlsxStyles holds a plain and a bold cell style for each column format, in
+// the order of sheetFormatStyles; the bold one is the plain index plus one
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="3"><numFmt numFmtId="164" formatCode="#,##0 &quot;₫&quot;"/><numFmt numFmtId="165" formatCode="#,##0.##"/><numFmt numFmtId="166" formatCode="dd/mm/yyyy hh:mm"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="8">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>
+<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="165" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>
+<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="166" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>
+</cellXfs>
+</styleSheet>`
+
+var sheetFormatStyles = map[string]int{
+	SheetFormatText:   0,
+	SheetFormatVND:    2,
+	SheetFormatNumber: 4,
+	SheetFormatDate:   6,
+}
+
+// WriteXLSX writes a workbook with a single sheet: a bold title row followed
+// by the rows, formatted by column
+func WriteXLSX(w io.Writer, sheetName string, columns []SheetColumn, rows []SheetRow) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="` + xmlEscape(xlsxSheetName(sheetName)) + `" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, file.content); err != nil {
+			return err
+		}
+	}
+
+	writer, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if err := writeXLSXSheet(writer, columns, rows); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeXLSXSheet(w io.Writer, columns []SheetColumn, rows []SheetRow) error {
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>
+<cols>`)
+	for i, column := range columns {
+		width := column.Width
+		if width == 0 {
+			width = 14
+		}
+		fmt.Fprintf(&sheet, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+	}
+	sheet.WriteString("</cols>\n<sheetData>\n")
+
+	title := SheetRow{Bold: true}
+	for _, column := range columns {
+		title.Cells = append(title.Cells, column.Title)
+	}
+	writeXLSXRow(&sheet, 1, columns, title, true)
+	for i, row := range rows {
+		writeXLSXRow(&sheet, i+2, columns, row, false)
+	}
+
+	sheet.WriteString("</sheetData>\n</worksheet>")
+	_, err := io.WriteString(w, sheet.String())
+	return err
+}
+
+func writeXLSXRow(sheet *strings.Builder, index int, columns []SheetColumn, row SheetRow, isTitle bool) {
+	fmt.Fprintf(sheet, `<row r="%d">`, index)
+	for i, value := range row.Cells {
+		if value == nil {
+			continue
+		}
+
+		style := 0
+		if i < len(columns) && !isTitle {
+			style = sheetFormatStyles[columns[i].Format]
+		}
+		if row.Bold {
+			style++
+		}
+
+		ref := xlsxColumnName(i) + strconv.Itoa(index)
+		switch v := value.(type) {
+		case time.Time:
+			if v.IsZero() {
+				continue
+			}
+			fmt.Fprintf(sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(xlsxSerial(v), 'f', -1, 64))
+		case float64:
+			fmt.Fprintf(sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+		case int, int64:
+			fmt.Fprintf(sheet, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+		default:
+			style = 0
+			if row.Bold {
+				style = 1
+			}
+			fmt.Fprintf(sheet, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(fmt.Sprint(v)))
+		}
+	}
+	sheet.WriteString("</row>\n")
+}
+
+// xlsxSerial turns a time into the day count Excel stores dates as
+func xlsxSerial(t time.Time) float64 {
+	t = t.In(time.Local)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local)
+	days := math.Round(midnight.Sub(epoch).Hours() / 24)
+	return days + t.Sub(midnight).Seconds()/86400
+}
+
+// xlsxColumnName turns a zero based column index into its letters
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxSheetName removes the characters Excel does not allow in sheet names
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
+
+func xmlEscape(value string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
+
+// WriteCSV writes the title row and the rows as UTF-8 CSV with a byte order
+// mark, so Excel shows Vietnamese text correctly. Numbers are written
+// without thousands separators and dates as 02/01/2006 15:04.
+func WriteCSV(w io.Writer, columns []SheetColumn, rows []SheetRow) error {
+	if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		titles[i] = column.Title
+	}
+	if err := writer.Write(titles); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(row.Cells))
+		for i, value := range row.Cells {
+			switch v := value.(type) {
+			case nil:
+			case time.Time:
+				if !v.IsZero() {
+					record[i] = v.In(time.Local).Format("02/01/2006 15:04")
+				}
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
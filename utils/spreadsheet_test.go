@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSpreadsheetNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"", 0},
+		{"42", 42},
+		{"1.5", 1.5},
+		{"2,5", 2.5},
+		{"1.250.000", 1250000},
+		{"1,250,000", 1250000},
+		{"1.250,5", 1250.5},
+		{"1,250.5", 1250.5},
+		{" 1 250 000 ", 1250000},
+		{"1.250.000 ₫", 1250000},
+		{"500000đ", 500000},
+		{"750000 VND", 750000},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSpreadsheetNumber(test.value)
+		if err != nil {
+			t.Errorf("ParseSpreadsheetNumber(%q): unexpected error %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseSpreadsheetNumber(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+
+	for _, value := range []string{"abc", "1.2.3,4,5", "--1"} {
+		if _, err := ParseSpreadsheetNumber(value); err == nil {
+			t.Errorf("ParseSpreadsheetNumber(%q): expected an error", value)
+		}
+	}
+}
+
+func TestParseSpreadsheetAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"", 0},
+		{"250000", 250000},
+		{"250.000", 250000},
+		{"250,000", 250000},
+		{"1.250.000", 1250000},
+		{"1,250,000 ₫", 1250000},
+		{"12.5", 12.5},
+		{"12,5", 12.5},
+		{"1250.000", 1250},
+		{"1.250.000,5", 1250000.5},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSpreadsheetAmount(test.value)
+		if err != nil {
+			t.Errorf("ParseSpreadsheetAmount(%q): unexpected error %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseSpreadsheetAmount(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+
+	if _, err := ParseSpreadsheetAmount("free"); err == nil {
+		t.Error(`ParseSpreadsheetAmount("free"): expected an error`)
+	}
+}
+
+func TestParseSpreadsheetTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"45292", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)},
+		{"45292.5", time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)},
+		{"45366.354166667", time.Date(2024, 3, 15, 8, 30, 0, 0, time.Local)},
+		{"2024-03-15", time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)},
+		{"2024-03-15 08:30", time.Date(2024, 3, 15, 8, 30, 0, 0, time.Local)},
+		{"15/03/2024", time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)},
+		{"5/3/2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local)},
+		{"15/03/2024 08:30", time.Date(2024, 3, 15, 8, 30, 0, 0, time.Local)},
+		{" 15/03/2024 ", time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)},
+		{"2024-03-15T08:30:00+07:00", time.Date(2024, 3, 15, 8, 30, 0, 0, time.FixedZone("", 7*60*60))},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSpreadsheetTime(test.value)
+		if err != nil {
+			t.Errorf("ParseSpreadsheetTime(%q): unexpected error %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("ParseSpreadsheetTime(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+
+	for _, value := range []string{"", "tomorrow", "03/15/2024", "32/01/2024", "-5"} {
+		if _, err := ParseSpreadsheetTime(value); err == nil {
+			t.Errorf("ParseSpreadsheetTime(%q): expected an error", value)
+		}
+	}
+}
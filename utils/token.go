@@ -1,34 +1,233 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
+	"log"
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
-func CreateToken(ttl time.Duration, payload interface{}, privateKey string) (string, error) {
-	decodedPrivateKey, err := base64.StdEncoding.DecodeString(privateKey)
-	if err != nil {
-		return "", fmt.Errorf("could not decode key: %w", err)
+// Token key uses, access and refresh tokens never share a signing key
+const (
+	KeyUseAccess  = "access"
+	KeyUseRefresh = "refresh"
+)
+
+// LegacyKeyID returns the key id given to the key pair from the environment.
+// Tokens signed before key ids existed carry no kid and are checked with it.
+func LegacyKeyID(use string) string {
+	return "env-" + use
+}
+
+// SigningKeyPEM is a stored signing key. PrivateKey may be empty for a key
+// that is only kept to verify tokens it already signed. Retired keys neither
+// sign nor verify.
+type SigningKeyPEM struct {
+	ID         string
+	Use        string
+	PrivateKey string
+	PublicKey  string
+	Retired    bool
+}
+
+// SigningKeySource returns the stored signing keys, retired ones included,
+// ordered from oldest to newest. The newest active key of each use signs new
+// tokens.
+type SigningKeySource func() ([]SigningKeyPEM, error)
+
+// unknownKeyReloadInterval limits how often a token with an unknown kid can
+// trigger a reload, so garbage tokens cannot hammer the key store
+const unknownKeyReloadInterval = 10 * time.Second
+
+type signingKey struct {
+	id         string
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+type keyRing struct {
+	signer *signingKey
+	keys   map[string]*signingKey
+}
+
+// TokenManager signs and verifies JWTs. Keys are parsed when they are loaded,
+// not on every request, and are identified by the kid header so a new key can
+// be introduced while tokens signed by the previous one stay valid.
+type TokenManager struct {
+	mu         sync.RWMutex
+	legacy     map[string]*signingKey
+	rings      map[string]keyRing
+	source     SigningKeySource
+	lastReload time.Time
+}
+
+// NewTokenManager parses the base64 encoded PEM keys from the environment.
+// They keep signing until keys are added to the source.
+func NewTokenManager(accessPrivateKey string, accessPublicKey string, refreshPrivateKey string, refreshPublicKey string, source SigningKeySource) (*TokenManager, error) {
+	manager := &TokenManager{legacy: make(map[string]*signingKey), source: source}
+
+	envKeys := []struct{ use, privateKey, publicKey string }{
+		{KeyUseAccess, accessPrivateKey, accessPublicKey},
+		{KeyUseRefresh, refreshPrivateKey, refreshPublicKey},
 	}
-	key, err := jwt.ParseRSAPrivateKeyFromPEM(decodedPrivateKey)
+	for _, envKey := range envKeys {
+		privatePEM, err := base64.StdEncoding.DecodeString(envKey.privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s token: could not decode key: %w", envKey.use, err)
+		}
+		publicPEM, err := base64.StdEncoding.DecodeString(envKey.publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s token: could not decode key: %w", envKey.use, err)
+		}
 
-	if err != nil {
-		return "", fmt.Errorf("create: parse key: %w", err)
+		key, err := parseSigningKey(SigningKeyPEM{ID: LegacyKeyID(envKey.use), Use: envKey.use, PrivateKey: string(privatePEM), PublicKey: string(publicPEM)})
+		if err != nil {
+			return nil, fmt.Errorf("%s token: %w", envKey.use, err)
+		}
+		manager.legacy[envKey.use] = key
+	}
+
+	if err := manager.Reload(); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// Reload fetches the keys from the source again. When the source has a
+// record for a legacy key id, the record wins, so retiring it there stops the
+// environment key from being accepted.
+func (m *TokenManager) Reload() error {
+	var stored []SigningKeyPEM
+	if m.source != nil {
+		var err error
+		if stored, err = m.source(); err != nil {
+			return fmt.Errorf("could not load signing keys: %w", err)
+		}
+	}
+
+	rings := make(map[string]keyRing)
+	for _, use := range []string{KeyUseAccess, KeyUseRefresh} {
+		rings[use] = keyRing{keys: make(map[string]*signingKey)}
+	}
+
+	known := make(map[string]bool)
+	for _, record := range stored {
+		known[record.ID] = true
+		ring, ok := rings[record.Use]
+		if !ok || record.Retired {
+			continue
+		}
+
+		key, err := parseSigningKey(record)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", record.ID, err)
+		}
+		ring.keys[key.id] = key
+		if key.privateKey != nil {
+			ring.signer = key
+		}
+		rings[record.Use] = ring
+	}
+
+	for use, key := range m.legacy {
+		if known[key.id] {
+			continue
+		}
+		ring := rings[use]
+		ring.keys[key.id] = key
+		if ring.signer == nil {
+			ring.signer = key
+		}
+		rings[use] = ring
+	}
+
+	m.mu.Lock()
+	m.rings = rings
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// StartReloading reloads the keys in the background so keys added or retired
+// by the admin command reach every running server
+func (m *TokenManager) StartReloading(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := m.Reload(); err != nil {
+				log.Println("Failed to reload signing keys:", err)
+			}
+		}
+	}()
+}
+
+// CreateAccessToken signs an access token for the payload with the extra claims
+func (m *TokenManager) CreateAccessToken(ttl time.Duration, payload interface{}, extraClaims map[string]interface{}) (string, error) {
+	return m.createToken(KeyUseAccess, ttl, payload, extraClaims)
+}
+
+// CreateRefreshToken signs a refresh token for the payload with the extra claims
+func (m *TokenManager) CreateRefreshToken(ttl time.Duration, payload interface{}, extraClaims map[string]interface{}) (string, error) {
+	return m.createToken(KeyUseRefresh, ttl, payload, extraClaims)
+}
+
+// ValidateAccessToken validates an access token and returns all of its claims
+func (m *TokenManager) ValidateAccessToken(token string) (jwt.MapClaims, error) {
+	return m.validateToken(KeyUseAccess, token)
+}
+
+// ValidateRefreshToken validates a refresh token and returns all of its claims
+func (m *TokenManager) ValidateRefreshToken(token string) (jwt.MapClaims, error) {
+	return m.validateToken(KeyUseRefresh, token)
+}
+
+// PublicKeys returns the keys that currently verify tokens of the given use
+func (m *TokenManager) PublicKeys(use string) map[string]*rsa.PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make(map[string]*rsa.PublicKey)
+	for id, key := range m.rings[use].keys {
+		keys[id] = key.publicKey
+	}
+	return keys
+}
+
+func (m *TokenManager) createToken(use string, ttl time.Duration, payload interface{}, extraClaims map[string]interface{}) (string, error) {
+	m.mu.RLock()
+	signer := m.rings[use].signer
+	m.mu.RUnlock()
+
+	if signer == nil {
+		return "", fmt.Errorf("create: no %s signing key", use)
 	}
 
 	now := time.Now().UTC()
 
 	claims := make(jwt.MapClaims)
+	for name, value := range extraClaims {
+		claims[name] = value
+	}
 	claims["sub"] = payload
+	claims["jti"] = uuid.New().String()
 	claims["exp"] = now.Add(ttl).Unix()
 	claims["iat"] = now.Unix()
 	claims["nbf"] = now.Unix()
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	jwtToken.Header["kid"] = signer.id
 
+	token, err := jwtToken.SignedString(signer.privateKey)
 	if err != nil {
 		return "", fmt.Errorf("create: sign token: %w", err)
 	}
@@ -36,22 +235,21 @@ func CreateToken(ttl time.Duration, payload interface{}, privateKey string) (str
 	return token, nil
 }
 
-func ValidateToken(token string, publicKey string) (interface{}, error) {
-	decodedPublicKey, err := base64.StdEncoding.DecodeString(publicKey)
-	if err != nil {
-		return nil, fmt.Errorf("could not decode: %w", err)
-	}
-
-	key, err := jwt.ParseRSAPublicKeyFromPEM(decodedPublicKey)
-
-	if err != nil {
-		return "", fmt.Errorf("validate: parse key: %w", err)
-	}
-
+func (m *TokenManager) validateToken(use string, token string) (jwt.MapClaims, error) {
 	parsedToken, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected method: %s", t.Header["alg"])
 		}
+
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			kid = LegacyKeyID(use)
+		}
+
+		key := m.verificationKey(use, kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key")
+		}
 		return key, nil
 	})
 
@@ -64,5 +262,108 @@ func ValidateToken(token string, publicKey string) (interface{}, error) {
 		return nil, fmt.Errorf("validate: invalid token")
 	}
 
-	return claims["sub"], nil
+	return claims, nil
+}
+
+// verificationKey looks the key up by id. A key added by another server may
+// not be loaded yet, so an unknown id triggers a reload now and then.
+func (m *TokenManager) verificationKey(use string, kid string) *rsa.PublicKey {
+	m.mu.RLock()
+	key, ok := m.rings[use].keys[kid]
+	stale := time.Since(m.lastReload) > unknownKeyReloadInterval
+	m.mu.RUnlock()
+
+	if ok {
+		return key.publicKey
+	}
+	if !stale || m.source == nil {
+		return nil
+	}
+
+	if err := m.Reload(); err != nil {
+		log.Println("Failed to reload signing keys:", err)
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if key, ok := m.rings[use].keys[kid]; ok {
+		return key.publicKey
+	}
+	return nil
+}
+
+func parseSigningKey(record SigningKeyPEM) (*signingKey, error) {
+	key := &signingKey{id: record.ID}
+
+	if record.PrivateKey != "" {
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(record.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("create: parse key: %w", err)
+		}
+		key.privateKey = privateKey
+	}
+
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(record.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("validate: parse key: %w", err)
+	}
+	key.publicKey = publicKey
+
+	return key, nil
+}
+
+// GenerateSigningKeyPEM creates a new RSA key pair encoded as PEM
+func GenerateSigningKeyPEM(bits int) (string, string, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", "", fmt.Errorf("could not generate key: %w", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("could not encode public key: %w", err)
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	return string(privatePEM), string(publicPEM), nil
+}
+
+// HashToken returns the SHA-256 hex digest used to store tokens server-side
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateRandomToken returns a URL safe random token of the given byte length
+func GenerateRandomToken(length int) (string, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("could not generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// JSONWebKey is the public part of a signing key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// NewJSONWebKey encodes an RSA public key used to verify RS256 signatures
+func NewJSONWebKey(kid string, key *rsa.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     kid,
+		Modulus:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
 }
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors, "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	// The RFC 6238 vectors are 8 digits, a 6 digit code is their last 6
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, test := range tests {
+		if got := totpCode(key, test.unix/totpPeriod); got != test.want {
+			t.Errorf("code at %d = %s, want %s", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	got, ok := ValidateTOTP(rfc6238Secret, "050471", now)
+	if !ok || got != step {
+		t.Errorf("current code: got (%d, %v), want (%d, true)", got, ok, step)
+	}
+
+	// One period of clock skew either way is accepted
+	key := []byte("12345678901234567890")
+	for _, offset := range []int64{-1, 1} {
+		code := totpCode(key, step+offset)
+		if got, ok := ValidateTOTP(rfc6238Secret, code, now); !ok || got != step+offset {
+			t.Errorf("code %d steps away: got (%d, %v), want (%d, true)", offset, got, ok, step+offset)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		code := totpCode(key, step+offset)
+		if code == totpCode(key, step) {
+			continue
+		}
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("code %d steps away was accepted", offset)
+		}
+	}
+
+	if _, ok := ValidateTOTP(" "+strings.ToLower(rfc6238Secret)+" ", "050 471", now); !ok {
+		t.Error("a lower case secret and a code with a space were refused")
+	}
+
+	for _, code := range []string{"", "05047", "0504712", "123456", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "050471", now); ok {
+		t.Error("an invalid secret was accepted")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("got a %d byte key, want 20", len(key))
+	}
+
+	other, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Error("two secrets are the same")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Logistics App", "an@example.com", rfc6238Secret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("got %s://%s, want otpauth://totp", parsed.Scheme, parsed.Host)
+	}
+	if !strings.HasPrefix(uri, "otpauth://totp/Logistics%20App%3Aan%40example.com?") {
+		t.Errorf("unexpected label in %s", uri)
+	}
+
+	query := parsed.Query()
+	for key, want := range map[string]string{"secret": rfc6238Secret, "issuer": "Logistics App", "algorithm": "SHA1", "digits": "6", "period": "30"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || code != strings.ToLower(code) {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q was generated twice", code)
+		}
+		seen[code] = true
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionRequired means an update did not say which version of the
+// record it was based on, applying it could overwrite changes it never saw
+var ErrVersionRequired = errors.New("send the ETag of the record in the If-Match header or its version in the version field")
+
+// ErrVersionConflict means a record was changed by someone else since the
+// version an update expected
+var ErrVersionConflict = errors.New("the record was changed by someone else")
+
+// ETag returns the entity tag of a record version, sent in the ETag header
+// and expected back in If-Match
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ExpectedVersion returns the version of a record the client last read, so
+// an update only applies when nobody changed the record since. That is the
+// If-Match header when it holds an ETag, the version sent in the body
+// otherwise, 0 when none was sent. ErrVersionRequired is returned when
+// neither is there.
+func ExpectedVersion(ifMatch string, sent int) (int, error) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		if sent < 1 {
+			return 0, ErrVersionRequired
+		}
+		return sent, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid If-Match header, send the ETag of the record")
+	}
+	return version, nil
+}
+
+// SentVersion returns the version field of the JSON object in body, 0 when
+// it was not sent
+func SentVersion(body []byte) int {
+	var sent struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		return 0
+	}
+	return sent.Version
+}
+
+// SentColumns returns the columns of model whose JSON names are keys of the
+// JSON object in body, so an update only writes the fields a client sent.
+// The except columns are left out even when sent.
+func SentColumns(db *gorm.DB, model interface{}, body []byte, except ...string) ([]string, error) {
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(body, &sent); err != nil {
+		return nil, err
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	skip := make(map[string]bool, len(except))
+	for _, column := range except {
+		skip[column] = true
+	}
+
+	var columns []string
+	for _, field := range stmt.Schema.Fields {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.DBName == "" || skip[field.DBName] || name == "" || name == "-" {
+			continue
+		}
+		if _, ok := sent[name]; ok {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns, nil
+}
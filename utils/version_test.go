@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	if got := ETag(7); got != `"7"` {
+		t.Errorf(`ETag(7) = %s, want "7"`, got)
+	}
+}
+
+func TestExpectedVersion(t *testing.T) {
+	tests := []struct {
+		ifMatch string
+		sent    int
+		want    int
+		err     string
+	}{
+		{`"3"`, 5, 3, ""},
+		{`W/"3"`, 5, 3, ""},
+		{` "12" `, 0, 12, ""},
+		{"", 5, 5, ""},
+		{"*", 5, 5, ""},
+		{"", 0, 0, ErrVersionRequired.Error()},
+		{"*", 0, 0, ErrVersionRequired.Error()},
+		{"", -1, 0, ErrVersionRequired.Error()},
+		{`"abc"`, 5, 0, "invalid If-Match header, send the ETag of the record"},
+		{`"0"`, 5, 0, "invalid If-Match header, send the ETag of the record"},
+		{`"-2"`, 5, 0, "invalid If-Match header, send the ETag of the record"},
+	}
+
+	for _, test := range tests {
+		got, err := ExpectedVersion(test.ifMatch, test.sent)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("ExpectedVersion(%q, %d): got error %v, want %q", test.ifMatch, test.sent, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ExpectedVersion(%q, %d) = %d, %v, want %d", test.ifMatch, test.sent, got, err, test.want)
+		}
+	}
+}
+
+func TestSentVersion(t *testing.T) {
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"version": 4, "name": "A"}`, 4},
+		{`{"name": "A"}`, 0},
+		{`{"version": null}`, 0},
+		{`{"version": "4"}`, 0},
+		{`not json`, 0},
+	}
+
+	for _, test := range tests {
+		if got := SentVersion([]byte(test.body)); got != test.want {
+			t.Errorf("SentVersion(%s) = %d, want %d", test.body, got, test.want)
+		}
+	}
+}
+
+type versionTestRecord struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	TripCount int     `json:"trip_count"`
+	RefundFee float64 `json:"recovery_fee"`
+	Secret    string  `json:"-"`
+	Version   int     `json:"version"`
+	Internal  string
+}
+
+func TestSentColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		except []string
+		want   []string
+	}{
+		{name: "only sent fields", body: `{"name": "A", "trip_count": 0}`, want: []string{"name", "trip_count"}},
+		{name: "json name differs from the column", body: `{"recovery_fee": 10000}`, want: []string{"refund_fee"}},
+		{name: "null is sent", body: `{"name": null}`, want: []string{"name"}},
+		{name: "unknown and hidden fields", body: `{"nickname": "B", "Secret": "x", "Internal": "y"}`},
+		{name: "except columns", body: `{"id": "1", "name": "A", "version": 4}`, except: []string{"id", "version"}, want: []string{"name"}},
+		{name: "empty object", body: `{}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := SentColumns(dryRunDB(t), &versionTestRecord{}, []byte(test.body), test.except...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := SentColumns(dryRunDB(t), &versionTestRecord{}, []byte(`[1, 2]`)); err == nil {
+		t.Error("a body that is not an object was accepted")
+	}
+}